@@ -0,0 +1,334 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"strings"
+)
+
+// Color is the color of a stone on a Go board.
+type Color int8
+
+// These are the possible values for Color.  Empty is used for points
+// on the board which are not occupied by a stone.
+const (
+	Empty Color = iota
+	Black
+	White
+)
+
+// Opponent returns the color of the other player.  The opponent of Empty
+// is Empty.
+func (c Color) Opponent() Color {
+	switch c {
+	case Black:
+		return White
+	case White:
+		return Black
+	default:
+		return Empty
+	}
+}
+
+func (c Color) String() string {
+	switch c {
+	case Black:
+		return "black"
+	case White:
+		return "white"
+	default:
+		return "empty"
+	}
+}
+
+// These errors are returned by Board.Play for illegal moves.
+var (
+	ErrOffBoard = errors.New("point is not on the board")
+	ErrOccupied = errors.New("point is occupied")
+	ErrSuicide  = errors.New("suicide is not allowed")
+	ErrKo       = errors.New("ko recapture is not allowed")
+)
+
+// A Board represents the stones on a Go board, and keeps track of captured
+// stones and of simple ko.
+type Board struct {
+	Size  BoardSize
+	Rules Rules
+
+	stones   []Color
+	hash     uint64 // Zobrist hash of stones
+	zobrist  *zobristTable
+	ko       Move   // point where a ko recapture is not allowed, or Pass
+	koColor  Color  // color of the player who must not retake the ko
+	captures [3]int // number of stones captured, indexed by the capturer
+
+	// scratch space for group computations
+	mark    []uint32
+	markGen uint32
+	queue   []int
+}
+
+// NewBoard returns an empty board of the given size.
+func NewBoard(sz BoardSize) *Board {
+	n := sz.Width * sz.Height
+	return &Board{
+		Size:    sz,
+		stones:  make([]Color, n),
+		zobrist: getZobristTable(sz),
+		ko:      Pass,
+		mark:    make([]uint32, n),
+	}
+}
+
+// Clone returns a copy of the board.
+func (b *Board) Clone() *Board {
+	res := *b
+	res.stones = append([]Color(nil), b.stones...)
+	res.mark = make([]uint32, len(b.mark))
+	res.markGen = 0
+	res.queue = nil
+	return &res
+}
+
+// At returns the color of the stone at point p.  For points which are not
+// on the board, Empty is returned.
+func (b *Board) At(p Move) Color {
+	if !b.Size.contains(p) {
+		return Empty
+	}
+	return b.stones[b.index(p)]
+}
+
+// Set places a stone of color c at point p, or removes the stone at p if
+// c is Empty.  This is used for setup properties like AB, AW and AE:
+// no stones are captured, and any ko restriction is lifted.  Points not on
+// the board are ignored.
+func (b *Board) Set(p Move, c Color) {
+	if !b.Size.contains(p) {
+		return
+	}
+	b.setStone(b.index(p), c)
+	b.ko = Pass
+}
+
+// Ko returns the point where the next move must not be played because of
+// the simple ko rule.  If there is no such point, Pass is returned.
+func (b *Board) Ko() Move {
+	return b.ko
+}
+
+// Hash returns a Zobrist hash of the stones on the board.  Equal
+// positions on boards of the same size have equal hashes.  The hash does
+// not depend on captures, ko or the player to move; see also
+// Position.SituationalHash.
+func (b *Board) Hash() uint64 {
+	return b.hash
+}
+
+// Captures returns the number of stones captured by the player of
+// color c so far.
+func (b *Board) Captures(c Color) int {
+	if c != Black && c != White {
+		return 0
+	}
+	return b.captures[c]
+}
+
+// Play plays a move for the player of color c, and returns the stones
+// removed from the board.  Normally these are the captured stones of the
+// opponent; if the move is a suicide which is allowed by the rules, the
+// player's own stones are returned instead.  A pass is always legal.
+// If the move is illegal, the board is left unchanged and one of ErrOffBoard,
+// ErrOccupied, ErrKo or ErrSuicide is returned.
+func (b *Board) Play(c Color, m Move) ([]Move, error) {
+	if m.IsPass() {
+		b.ko = Pass
+		return nil, nil
+	}
+	if !b.Size.contains(m) {
+		return nil, ErrOffBoard
+	}
+	idx := b.index(m)
+	if b.stones[idx] != Empty {
+		return nil, ErrOccupied
+	}
+	if m == b.ko && c == b.koColor {
+		return nil, ErrKo
+	}
+
+	b.setStone(idx, c)
+	var captured []Move
+	opp := c.Opponent()
+	b.forNeighbours(idx, func(j int) {
+		if b.stones[j] == opp && !b.hasLiberties(j) {
+			captured = b.removeGroup(j, captured)
+		}
+	})
+
+	b.ko = Pass
+	if len(captured) == 0 && !b.hasLiberties(idx) {
+		if !b.Rules.AllowSuicide {
+			b.setStone(idx, Empty)
+			return nil, ErrSuicide
+		}
+		own := b.removeGroup(idx, nil)
+		b.captures[opp] += len(own)
+		return own, nil
+	}
+
+	if len(captured) == 1 && b.isKoShape(idx) {
+		b.ko = captured[0]
+		b.koColor = opp
+	}
+	b.captures[c] += len(captured)
+	return captured, nil
+}
+
+// isKoShape reports whether the stone at idx, which has just captured a
+// single stone, forms a group of one stone with exactly one liberty.
+func (b *Board) isKoShape(idx int) bool {
+	c := b.stones[idx]
+	ownNeighbours := 0
+	liberties := 0
+	b.forNeighbours(idx, func(j int) {
+		switch b.stones[j] {
+		case c:
+			ownNeighbours++
+		case Empty:
+			liberties++
+		}
+	})
+	return ownNeighbours == 0 && liberties == 1
+}
+
+// hasLiberties reports whether the group containing the stone at idx has
+// at least one liberty.
+func (b *Board) hasLiberties(idx int) bool {
+	found := false
+	b.visitGroup(idx, func(j int) bool {
+		b.forNeighbours(j, func(k int) {
+			if b.stones[k] == Empty {
+				found = true
+			}
+		})
+		return !found
+	})
+	return found
+}
+
+// removeGroup removes the group containing the stone at idx from the
+// board, and appends the removed points to res.
+func (b *Board) removeGroup(idx int, res []Move) []Move {
+	var group []int
+	b.visitGroup(idx, func(j int) bool {
+		group = append(group, j)
+		return true
+	})
+	for _, j := range group {
+		b.setStone(j, Empty)
+		res = append(res, b.point(j))
+	}
+	return res
+}
+
+// visitGroup calls fn for every stone in the group containing the stone
+// at idx, until fn returns false.
+func (b *Board) visitGroup(idx int, fn func(int) bool) {
+	b.markGen++
+	if b.markGen == 0 {
+		for i := range b.mark {
+			b.mark[i] = 0
+		}
+		b.markGen = 1
+	}
+
+	c := b.stones[idx]
+	b.mark[idx] = b.markGen
+	queue := append(b.queue[:0], idx)
+	for len(queue) > 0 {
+		j := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if !fn(j) {
+			break
+		}
+		b.forNeighbours(j, func(k int) {
+			if b.stones[k] == c && b.mark[k] != b.markGen {
+				b.mark[k] = b.markGen
+				queue = append(queue, k)
+			}
+		})
+	}
+	b.queue = queue
+}
+
+// forNeighbours calls fn for each of the (up to four) points adjacent to
+// the point with index idx.
+func (b *Board) forNeighbours(idx int, fn func(int)) {
+	w := b.Size.Width
+	x := idx % w
+	if x > 0 {
+		fn(idx - 1)
+	}
+	if x < w-1 {
+		fn(idx + 1)
+	}
+	if idx >= w {
+		fn(idx - w)
+	}
+	if idx+w < len(b.stones) {
+		fn(idx + w)
+	}
+}
+
+// setStone changes the stone at idx and updates the hash.
+func (b *Board) setStone(idx int, c Color) {
+	b.hash ^= b.zobrist.key(idx, b.stones[idx]) ^ b.zobrist.key(idx, c)
+	b.stones[idx] = c
+}
+
+func (b *Board) index(p Move) int {
+	return int(p.Y)*b.Size.Width + int(p.X)
+}
+
+func (b *Board) point(idx int) Move {
+	w := b.Size.Width
+	return Move{int8(idx % w), int8(idx / w)}
+}
+
+// String returns a diagram of the board, with the top row first.  Black
+// stones are shown as "X", white stones as "O", and empty points as ".".
+func (b *Board) String() string {
+	var sb strings.Builder
+	w := b.Size.Width
+	for y := b.Size.Height - 1; y >= 0; y-- {
+		for x := 0; x < w; x++ {
+			switch b.stones[y*w+x] {
+			case Black:
+				sb.WriteByte('X')
+			case White:
+				sb.WriteByte('O')
+			default:
+				sb.WriteByte('.')
+			}
+		}
+		if y > 0 {
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,329 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// boardFromDiagram creates a board from a diagram in the format used by
+// Board.String.
+func boardFromDiagram(t *testing.T, diagram string) *Board {
+	t.Helper()
+	rows := strings.Split(strings.TrimSpace(diagram), "\n")
+	sz := BoardSize{len(strings.TrimSpace(rows[0])), len(rows)}
+	b := NewBoard(sz)
+	for i, row := range rows {
+		row = strings.TrimSpace(row)
+		if len(row) != sz.Width {
+			t.Fatalf("row %d has wrong length", i)
+		}
+		for x := 0; x < sz.Width; x++ {
+			p := Move{int8(x), int8(sz.Height - 1 - i)}
+			switch row[x] {
+			case 'X':
+				b.Set(p, Black)
+			case 'O':
+				b.Set(p, White)
+			}
+		}
+	}
+	return b
+}
+
+func normalizeDiagram(diagram string) string {
+	rows := strings.Split(strings.TrimSpace(diagram), "\n")
+	for i, row := range rows {
+		rows[i] = strings.TrimSpace(row)
+	}
+	return strings.Join(rows, "\n")
+}
+
+func TestBoardPlay(t *testing.T) {
+	cases := []struct {
+		name     string
+		before   string
+		color    Color
+		move     string // in SGF coordinates
+		suicide  bool
+		err      error
+		captured int
+		after    string
+	}{
+		{
+			name: "corner capture",
+			before: `
+				O....
+				X....
+				.....`,
+			color: Black, move: "ba", captured: 1,
+			after: `
+				.X...
+				X....
+				.....`,
+		},
+		{
+			name: "edge capture of two stones",
+			before: `
+				XOO..
+				.XX..
+				.....`,
+			color: Black, move: "da", captured: 2,
+			after: `
+				X..X.
+				.XX..
+				.....`,
+		},
+		{
+			name: "group keeps a liberty",
+			before: `
+				.XXX.
+				.OOO.
+				.XXX.`,
+			color: Black, move: "ab", captured: 0,
+			after: `
+				.XXX.
+				XOOO.
+				.XXX.`,
+		},
+		{
+			name: "group capture, last liberty",
+			before: `
+				XX.X.
+				XOOOX
+				.XXXX`,
+			color: Black, move: "ca", captured: 3,
+			after: `
+				XXXX.
+				X...X
+				.XXXX`,
+		},
+		{
+			name: "capture inside an eye",
+			before: `
+				.OX..
+				OX.XO
+				.OX..`,
+			color: White, move: "cb", captured: 1,
+			after: `
+				.OX..
+				O.OXO
+				.OX..`,
+		},
+		{
+			name: "capture of two groups",
+			before: `
+				OX.XO
+				.O.O.
+				.....`,
+			color: White, move: "ca", captured: 2,
+			after: `
+				O.O.O
+				.O.O.
+				.....`,
+		},
+		{
+			name: "capture which looks like suicide",
+			before: `
+				.XO..
+				XO...
+				O....`,
+			color: White, move: "aa", captured: 2,
+			after: `
+				O.O..
+				.O...
+				O....`,
+		},
+		{
+			name: "single stone suicide",
+			before: `
+				.X...
+				X....
+				.....`,
+			color: White, move: "aa", err: ErrSuicide,
+			after: `
+				.X...
+				X....
+				.....`,
+		},
+		{
+			name: "multi-stone suicide forbidden",
+			before: `
+				O.X..
+				XX...
+				.....`,
+			color: White, move: "ba", err: ErrSuicide,
+			after: `
+				O.X..
+				XX...
+				.....`,
+		},
+		{
+			name: "multi-stone suicide allowed",
+			before: `
+				O.X..
+				XX...
+				.....`,
+			color: White, move: "ba", suicide: true, captured: 2,
+			after: `
+				..X..
+				XX...
+				.....`,
+		},
+		{
+			name: "occupied",
+			before: `
+				X....
+				.....
+				.....`,
+			color: White, move: "aa", err: ErrOccupied,
+			after: `
+				X....
+				.....
+				.....`,
+		},
+		{
+			name: "off board",
+			before: `
+				.....
+				.....
+				.....`,
+			color: White, move: "fa", err: ErrOffBoard,
+			after: `
+				.....
+				.....
+				.....`,
+		},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			b := boardFromDiagram(t, test.before)
+			b.Rules.AllowSuicide = test.suicide
+			m := Move{int8(test.move[0] - 'a'), int8(b.Size.Height - 1 - int(test.move[1]-'a'))}
+			captured, err := b.Play(test.color, m)
+			if err != test.err {
+				t.Errorf("expected error %v, got %v", test.err, err)
+			}
+			if len(captured) != test.captured {
+				t.Errorf("expected %d captured stones, got %d", test.captured, len(captured))
+			}
+			if got, want := b.String(), normalizeDiagram(test.after); got != want {
+				t.Errorf("wrong position, expected\n%s\ngot\n%s", want, got)
+			}
+		})
+	}
+}
+
+func TestBoardKo(t *testing.T) {
+	b := boardFromDiagram(t, `
+		.XO..
+		XO.O.
+		.XO..
+		.....`)
+	sz := b.Size
+	play := func(c Color, val string) error {
+		t.Helper()
+		m, err := sz.ParseMove(val)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = b.Play(c, m)
+		return err
+	}
+
+	// black takes the ko
+	if err := play(Black, "cb"); err != nil {
+		t.Fatal(err)
+	}
+	if b.Ko() != (Move{1, 2}) {
+		t.Errorf("expected ko at (1,2), got %v", b.Ko())
+	}
+	// white cannot retake immediately
+	if err := play(White, "bb"); !errors.Is(err, ErrKo) {
+		t.Fatalf("expected ErrKo, got %v", err)
+	}
+	// ko threat and answer
+	if err := play(White, "ed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := play(Black, "dd"); err != nil {
+		t.Fatal(err)
+	}
+	// now white can retake
+	if err := play(White, "bb"); err != nil {
+		t.Fatal(err)
+	}
+	if b.Ko() != (Move{2, 2}) {
+		t.Errorf("expected ko at (2,2), got %v", b.Ko())
+	}
+	// a pass lifts the ko restriction
+	if err := play(Black, ""); err != nil {
+		t.Fatal(err)
+	}
+	if b.Ko() != Pass {
+		t.Errorf("ko not lifted by pass")
+	}
+	if err := play(Black, "cb"); err != nil {
+		t.Fatal(err)
+	}
+
+	if b.Captures(Black) != 2 || b.Captures(White) != 1 {
+		t.Errorf("wrong capture counts %d/%d", b.Captures(Black), b.Captures(White))
+	}
+}
+
+func TestBoardNoKo(t *testing.T) {
+	// The capturing stone is connected to other stones.
+	b := boardFromDiagram(t, `
+		XO...
+		.O...
+		.....`)
+	captured, err := b.Play(White, Move{0, 1})
+	if err != nil || len(captured) != 1 {
+		t.Fatalf("unexpected result %v, %v", captured, err)
+	}
+	if b.Ko() != Pass {
+		t.Errorf("unexpected ko at %v", b.Ko())
+	}
+
+	// The capturing stone has two liberties after the capture.
+	b = boardFromDiagram(t, `
+		XO..
+		....`)
+	captured, err = b.Play(White, Move{0, 0})
+	if err != nil || len(captured) != 1 {
+		t.Fatalf("unexpected result %v, %v", captured, err)
+	}
+	if b.Ko() != Pass {
+		t.Errorf("unexpected ko at %v", b.Ko())
+	}
+}
+
+func TestBoardClone(t *testing.T) {
+	b := NewBoard(BoardSize{9, 9})
+	_, _ = b.Play(Black, Move{4, 4})
+	c := b.Clone()
+	_, _ = c.Play(White, Move{3, 3})
+	if b.At(Move{3, 3}) != Empty {
+		t.Error("clone shares state with the original board")
+	}
+	if c.At(Move{4, 4}) != Black {
+		t.Error("clone lost a stone")
+	}
+}
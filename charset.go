@@ -0,0 +1,175 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Charset converts text between a character encoding and UTF-8.
+//
+// Encodings in which the bytes of the ASCII characters "]" and "\" can
+// occur as part of a multi-byte character, for example Shift_JIS and
+// Big5, must implement MultiByteCharset, so that these bytes are not
+// mistaken for SGF syntax.
+type Charset interface {
+	// Decode converts s from the encoding to UTF-8.
+	Decode(s string) (string, error)
+
+	// Encode converts the UTF-8 string s to the encoding.
+	Encode(s string) (string, error)
+}
+
+// A MultiByteCharset is a Charset for an encoding in which some characters
+// are represented by two bytes, and where the second byte may coincide
+// with an ASCII character.  When reading a game tree in such an encoding,
+// the second byte of each two-byte character is skipped while looking for
+// the end of a property value and for escape characters.
+type MultiByteCharset interface {
+	Charset
+
+	// IsLeadByte reports whether b is the first byte of a two-byte
+	// character.
+	IsLeadByte(b byte) bool
+}
+
+var (
+	charsetMutex sync.RWMutex
+	charsets     = map[string]Charset{
+		"UTF8":      utf8Charset{},
+		"USASCII":   asciiCharset{},
+		"ASCII":     asciiCharset{},
+		"ISO88591":  latin1Charset{},
+		"LATIN1":    latin1Charset{},
+		"ISOLATIN1": latin1Charset{},
+	}
+)
+
+// RegisterCharset makes a character set available for reading and
+// writing SGF files.  The name is matched against the value of the CA
+// property, ignoring case as well as hyphens, underscores and spaces.
+// A Charset registered for an existing name replaces the previous one.
+//
+// The package only provides UTF-8, US-ASCII and ISO-8859-1 itself.
+// Other character sets, for example from golang.org/x/text/encoding,
+// can be registered by the caller.
+func RegisterCharset(name string, cs Charset) {
+	charsetMutex.Lock()
+	defer charsetMutex.Unlock()
+	charsets[normalizeCharsetName(name)] = cs
+}
+
+// LookupCharset returns the Charset registered under the given name.
+// If no such character set is known, nil is returned.
+func LookupCharset(name string) Charset {
+	charsetMutex.RLock()
+	defer charsetMutex.RUnlock()
+	return charsets[normalizeCharsetName(name)]
+}
+
+func normalizeCharsetName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '_', ' ':
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, strings.TrimSpace(name))
+}
+
+// transcode converts all property values in the game tree t from the
+// character set cs to UTF-8.  Values which cannot be converted are left
+// unchanged and reported via warn.  If all values were converted, the CA
+// property of the root node is set to "UTF-8"; otherwise it is left
+// unchanged, since the tree still contains text in the old encoding.
+func transcode(t *Tree, cs Charset, warn func(*Tree, error)) {
+	if _, isUTF8 := cs.(utf8Charset); isUTF8 {
+		return
+	}
+
+	ok := true
+	todo := []*Tree{t}
+	for len(todo) > 0 {
+		n := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		todo = append(todo, n.Children...)
+
+		for key, values := range n.Properties {
+			for i, val := range values {
+				conv, err := cs.Decode(val)
+				if err != nil {
+					warn(n, newPropertyError(key, val, err))
+					ok = false
+					continue
+				}
+				values[i] = conv
+			}
+		}
+	}
+	if ok {
+		t.Properties["CA"] = []string{"UTF-8"}
+	}
+}
+
+// errEncoding is returned by the built-in character sets for strings
+// which cannot be converted.
+var errEncoding = errors.New("invalid character for encoding")
+
+type utf8Charset struct{}
+
+func (utf8Charset) Decode(s string) (string, error) { return s, nil }
+func (utf8Charset) Encode(s string) (string, error) { return s, nil }
+
+type asciiCharset struct{}
+
+func (asciiCharset) Decode(s string) (string, error) { return checkASCII(s) }
+func (asciiCharset) Encode(s string) (string, error) { return checkASCII(s) }
+
+func checkASCII(s string) (string, error) {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return "", fmt.Errorf("%w: byte 0x%02x", errEncoding, s[i])
+		}
+	}
+	return s, nil
+}
+
+type latin1Charset struct{}
+
+func (latin1Charset) Decode(s string) (string, error) {
+	res := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		res[i] = rune(s[i])
+	}
+	return string(res), nil
+}
+
+func (latin1Charset) Encode(s string) (string, error) {
+	res := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			return "", fmt.Errorf("%w: %q", errEncoding, r)
+		}
+		res = append(res, byte(r))
+	}
+	return string(res), nil
+}
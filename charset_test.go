@@ -0,0 +1,265 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadLatin1(t *testing.T) {
+	in := "(;CA[ISO-8859-1]PB[M\xfcller])(;PB[M\xc3\xbcller])"
+	c, err := Read(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tree := range c {
+		pb, err := tree.GetSimpleText("PB")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pb != "Müller" {
+			t.Errorf("%d: expected %q, got %q", i, "Müller", pb)
+		}
+	}
+	if ca := c[0].Properties["CA"]; len(ca) != 1 || ca[0] != "UTF-8" {
+		t.Errorf("expected CA[UTF-8], got %q", ca)
+	}
+}
+
+func TestReadCharsetOverride(t *testing.T) {
+	in := "(;CA[UTF-8]PB[M\xfcller])"
+	c, _, err := ReadWithOptions(strings.NewReader(in), &ReadOptions{Charset: "latin1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, _ := c[0].GetSimpleText("PB")
+	if pb != "Müller" {
+		t.Errorf("expected %q, got %q", "Müller", pb)
+	}
+}
+
+func TestReadInvalidCharacter(t *testing.T) {
+	in := "(;CA[US-ASCII]PB[M\xfcller]PW[Meier])"
+	c, warnings, err := ReadWithOptions(strings.NewReader(in), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected one warning, got %v", warnings)
+	}
+	if ca := c[0].Properties["CA"]; len(ca) != 1 || ca[0] != "US-ASCII" {
+		t.Errorf("expected CA[US-ASCII], got %q", ca)
+	}
+	if pb := c[0].Properties["PB"]; len(pb) != 1 || pb[0] != "M\xfcller" {
+		t.Errorf("expected raw PB value, got %q", pb)
+	}
+}
+
+func TestReadUnknownCharset(t *testing.T) {
+	in := "(;CA[X-Unknown]PB[M\xfcller])"
+	c, warnings, err := ReadWithOptions(strings.NewReader(in), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Line != 1 {
+		t.Errorf("expected one warning, got %v", warnings)
+	}
+	if pb := c[0].Properties["PB"][0]; pb != "M\xfcller" {
+		t.Errorf("value was modified: %q", pb)
+	}
+}
+
+// upperCharset is a toy character set, in which all letters are
+// represented in upper case.
+type upperCharset struct{}
+
+func (upperCharset) Decode(s string) (string, error) {
+	return strings.ToLower(s), nil
+}
+
+func (upperCharset) Encode(s string) (string, error) {
+	if strings.ContainsRune(s, '!') {
+		return "", errors.New("no exclamation marks")
+	}
+	return strings.ToUpper(s), nil
+}
+
+func TestRegisterCharset(t *testing.T) {
+	RegisterCharset("X-Upper", upperCharset{})
+
+	in := "(;CA[x_upper]GN[HELLO])"
+	c, err := Read(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gn, _ := c[0].GetSimpleText("GN")
+	if gn != "hello" {
+		t.Errorf("expected %q, got %q", "hello", gn)
+	}
+
+	buf := &bytes.Buffer{}
+	err = c.WriteWithOptions(buf, &WriteOptions{Charset: "X-Upper"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "(;CA[X-UPPER]\nGN[HELLO])\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+	if ca := c[0].Properties["CA"][0]; ca != "UTF-8" {
+		t.Errorf("collection was modified: CA[%s]", ca)
+	}
+
+	c[0].Properties["GN"] = []string{"hello!"}
+	err = c.WriteWithOptions(buf, &WriteOptions{Charset: "X-Upper"})
+	var pe *PropertyError
+	if !errors.As(err, &pe) || pe.Name != "GN" {
+		t.Errorf("expected PropertyError for GN, got %v", err)
+	}
+}
+
+func TestWriteLatin1(t *testing.T) {
+	c := Collection{{Properties: Properties{"PB": []string{"Müller"}}}}
+	buf := &bytes.Buffer{}
+	err := c.WriteWithOptions(buf, &WriteOptions{Charset: "ISO-8859-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "(;CA[ISO-8859-1]\nPB[M\xfcller])\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	err = c.WriteWithOptions(buf, &WriteOptions{Charset: "X-Missing"})
+	if err == nil {
+		t.Error("unknown charset accepted")
+	}
+}
+
+// pairCharset is a toy two-byte character set, modelled on Shift_JIS:
+// every byte in the ranges 0x81-0x9F and 0xE0-0xFC starts a two-byte
+// character, and the second byte may be any byte from 0x40 to 0xFC.
+type pairCharset struct{}
+
+func (pairCharset) IsLeadByte(b byte) bool {
+	return b >= 0x81 && b <= 0x9F || b >= 0xE0 && b <= 0xFC
+}
+
+func (cs pairCharset) Decode(s string) (string, error) {
+	var res []rune
+	for i := 0; i < len(s); i++ {
+		if cs.IsLeadByte(s[i]) && i+1 < len(s) {
+			res = append(res, 0x10000+rune(s[i])<<8+rune(s[i+1]))
+			i++
+		} else {
+			res = append(res, rune(s[i]))
+		}
+	}
+	return string(res), nil
+}
+
+func (pairCharset) Encode(s string) (string, error) {
+	var res []byte
+	for _, r := range s {
+		if r >= 0x10000 {
+			res = append(res, byte(r>>8), byte(r))
+		} else {
+			res = append(res, byte(r))
+		}
+	}
+	return string(res), nil
+}
+
+func TestReadTrailByte(t *testing.T) {
+	RegisterCharset("X-Test-SJIS", pairCharset{})
+	yen := string(rune(0x1955C))     // encoded as "\x95\x5c"
+	bracket := string(rune(0x1955D)) // encoded as "\x95\x5d"
+
+	cases := []struct {
+		in  string
+		opt *ReadOptions
+	}{
+		{"(;CA[X-Test-SJIS]C[\x95\x5c]GN[x])", nil},
+		{"(;C[\x95\x5c]CA[X-Test-SJIS]GN[x])", nil},
+		{"(;C[\x95\x5c]GN[x])", &ReadOptions{Charset: "x_test_sjis"}},
+	}
+	for _, test := range cases {
+		c, _, err := ReadWithOptions(strings.NewReader(test.in), test.opt)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if comment, _ := c[0].GetText("C"); comment != yen {
+			t.Errorf("%q: wrong comment %q", test.in, comment)
+		}
+		if gn, _ := c[0].GetSimpleText("GN"); gn != "x" {
+			t.Errorf("%q: wrong game name %q", test.in, gn)
+		}
+	}
+
+	// the CA property is found even after a long value
+	long := strings.Repeat("long comment ", 1000)
+	in := "(;C[" + long + "\x95\x5c]CA[X-Test-SJIS]GN[x])" +
+		"(;GC[" + long + long + "\x95\x5c]CA[X-Test-SJIS]C[\x95\x5c])"
+	c, warnings, err := ReadWithOptions(strings.NewReader(in), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c) != 2 || len(warnings) != 0 {
+		t.Fatalf("wrong result %v, %v", c, warnings)
+	}
+	if comment, _ := c[0].GetText("C"); comment != long+yen {
+		t.Errorf("wrong long comment %.20q", comment)
+	}
+	if gn, _ := c[0].GetSimpleText("GN"); gn != "x" {
+		t.Errorf("wrong game name %q", gn)
+	}
+	if comment, _ := c[1].GetText("C"); comment != yen {
+		t.Errorf("wrong comment in second tree %q", comment)
+	}
+
+	// a trail byte 0x5D is not the end of the value, and an escaped
+	// two-byte character is kept intact
+	in = "(;CA[X-Test-SJIS]C[a\x95\x5d\\\x95\x5c\\]b];B[aa])"
+	c, err = Read(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment, _ := c[0].GetText("C"); comment != "a"+bracket+yen+"]b" {
+		t.Errorf("wrong comment %q", comment)
+	}
+	if len(c[0].Children) != 1 {
+		t.Error("following node not found")
+	}
+
+	// the write/read round trip preserves the value
+	buf := &bytes.Buffer{}
+	err = c.WriteWithOptions(buf, &WriteOptions{Charset: "X-Test-SJIS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment, _ := c2[0].GetText("C"); comment != "a"+bracket+yen+"]b" {
+		t.Errorf("wrong comment after round trip %q", comment)
+	}
+}
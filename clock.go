@@ -0,0 +1,268 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// OvertimeKind describes a system of overtime, as given in the OT property.
+type OvertimeKind int
+
+// These are the possible values for OvertimeKind.
+const (
+	// OvertimeUnknown is used if the OT property could not be decoded.
+	OvertimeUnknown OvertimeKind = iota
+
+	// OvertimeNone means that there is no overtime.
+	OvertimeNone
+
+	// OvertimeByoYomi gives the player Periods periods of PeriodTime
+	// seconds each.  A period is used up if a move takes longer than
+	// PeriodTime.
+	OvertimeByoYomi
+
+	// OvertimeCanadian requires the player to play Moves moves in
+	// PeriodTime seconds.  After this, a new period starts.
+	OvertimeCanadian
+
+	// OvertimeFischer adds Increment seconds to the player's clock after
+	// every move.
+	OvertimeFischer
+)
+
+// Overtime describes the overtime rules of a game.
+type Overtime struct {
+	Kind OvertimeKind
+
+	Periods    int     // number of byo-yomi periods
+	Moves      int     // number of moves per Canadian period
+	PeriodTime float64 // length of a byo-yomi or Canadian period, in seconds
+	Increment  float64 // Fischer increment, in seconds
+
+	// Text is the original value of the OT property.
+	Text string
+}
+
+var (
+	otDuration = `(\d+(?:\.\d+)?)\s*(s|sec|secs|seconds?|m|min|mins|minutes?)?`
+	otByoYomi  = regexp.MustCompile(`^(\d+)\s*[x×*]\s*` + otDuration + `\s*(?:byo-?\s*yomi|japanese)?$`)
+	otCanadian = regexp.MustCompile(`^(\d+)\s*/\s*` + otDuration + `\s*(?:canadian)?$`)
+	otFischer  = regexp.MustCompile(`^(?:fischer|increment)?\s*\+?\s*` + otDuration + `\s*(?:fischer|increment)?$`)
+)
+
+// ParseOvertime decodes the value of an OT property.  Recognised formats
+// include "5x30 byo-yomi", "25/600 Canadian" and "Fischer 10", ignoring
+// case; durations are in seconds unless followed by "min".  Since OT is a
+// free-form text, ParseOvertime never fails: values which are not
+// recognised give OvertimeUnknown, with the original value in Text.
+func ParseOvertime(val string) Overtime {
+	res := Overtime{Text: val}
+	s := strings.ToLower(strings.TrimSpace(val))
+
+	if m := otByoYomi.FindStringSubmatch(s); m != nil {
+		res.Kind = OvertimeByoYomi
+		res.Periods, _ = strconv.Atoi(m[1])
+		res.PeriodTime = otSeconds(m[2], m[3])
+	} else if m := otCanadian.FindStringSubmatch(s); m != nil {
+		res.Kind = OvertimeCanadian
+		res.Moves, _ = strconv.Atoi(m[1])
+		res.PeriodTime = otSeconds(m[2], m[3])
+	} else if m := otFischer.FindStringSubmatch(s); m != nil &&
+		(strings.Contains(s, "fischer") || strings.Contains(s, "increment")) {
+		res.Kind = OvertimeFischer
+		res.Increment = otSeconds(m[1], m[2])
+	} else if s == "none" || s == "-" || s == "" {
+		res.Kind = OvertimeNone
+	}
+	return res
+}
+
+// otSeconds converts a number and a unit, as matched by otDuration, into
+// seconds.
+func otSeconds(num, unit string) float64 {
+	x, _ := strconv.ParseFloat(num, 64)
+	if strings.HasPrefix(unit, "m") {
+		x *= 60
+	}
+	return x
+}
+
+// A TimeRecord gives the state of a player's clock after a move.  Values
+// which are not known are -1.
+type TimeRecord struct {
+	// Node is the node containing the move.
+	Node *Tree
+
+	// MoveNumber counts the moves (including passes) from the root, with
+	// the first move having number 1.
+	MoveNumber int
+
+	// TimeLeft is the time left after the move, in seconds, as given by
+	// the BL or WL property.  In overtime, this is the time left in the
+	// current period.
+	TimeLeft float64
+
+	// OvertimeLeft is the number of byo-yomi periods or of Canadian
+	// moves left after the move, as given by the OB or OW property.
+	OvertimeLeft int
+
+	// Spent is the time spent on the move, in seconds.
+	Spent float64
+}
+
+// TimeUsage describes the use of time along a variation of a game.
+type TimeUsage struct {
+	MainTime float64 // from the TM property, or -1 if not given
+	Overtime Overtime
+
+	Black, White []TimeRecord
+}
+
+// TimeUsage collects the clock information (BL, WL, OB and OW) along a
+// variation of the game with root t, using TM and OT from the game-info
+// node to determine the time spent on each move.  The variation starts
+// at the root and follows path; after the end of path the first child of
+// each node is followed, so that a nil path gives the main variation.
+func (t *Tree) TimeUsage(path Path) (*TimeUsage, error) {
+	res := &TimeUsage{MainTime: -1}
+	if info := t.GameInfoNode(); info != nil {
+		tm, err := info.GetReal("TM")
+		if err == nil {
+			res.MainTime = tm
+		} else if !errors.Is(err, ErrMissingProperty) {
+			return nil, info.setNode(err)
+		}
+		ot, err := info.GetSimpleText("OT")
+		if err == nil {
+			res.Overtime = ParseOvertime(ot)
+		} else if !errors.Is(err, ErrMissingProperty) {
+			return nil, info.setNode(err)
+		}
+	}
+
+	// the state of the clocks, indexed by color
+	left := [3]float64{-1, res.MainTime, res.MainTime}
+	overtime := [3]int{-1, -1, -1}
+
+	moveNumber := 0
+	n := t
+	for depth := 0; ; depth++ {
+		var c Color
+		if _, ok := n.Properties["B"]; ok {
+			c = Black
+		} else if _, ok := n.Properties["W"]; ok {
+			c = White
+		}
+		if c != Empty {
+			moveNumber++
+			rec, err := n.timeRecord(c)
+			if err != nil {
+				return nil, err
+			}
+			rec.MoveNumber = moveNumber
+			rec.Spent = res.Overtime.spent(left[c], overtime[c], rec.TimeLeft, rec.OvertimeLeft)
+			left[c], overtime[c] = rec.TimeLeft, rec.OvertimeLeft
+			if c == Black {
+				res.Black = append(res.Black, rec)
+			} else {
+				res.White = append(res.White, rec)
+			}
+		}
+
+		if depth < len(path) {
+			if path[depth] < 0 || path[depth] >= len(n.Children) {
+				return nil, ErrNoSuchNode
+			}
+			n = n.Children[path[depth]]
+		} else if len(n.Children) > 0 {
+			n = n.Children[0]
+		} else {
+			break
+		}
+	}
+	return res, nil
+}
+
+// timeRecord reads the clock properties for the player of color c from
+// the node t.
+func (t *Tree) timeRecord(c Color) (TimeRecord, error) {
+	rec := TimeRecord{Node: t, TimeLeft: -1, OvertimeLeft: -1}
+	name := c.propertyName()
+	x, err := t.GetReal(name + "L")
+	if err == nil && x >= 0 {
+		rec.TimeLeft = x
+	} else if err == nil {
+		return rec, t.setNode(newPropertyError(name+"L", t.Properties[name+"L"][0], ErrInvalidValue))
+	} else if !errors.Is(err, ErrMissingProperty) {
+		return rec, t.setNode(err)
+	}
+	k, err := t.GetNumber("O" + name)
+	if err == nil && k >= 0 {
+		rec.OvertimeLeft = k
+	} else if err == nil {
+		return rec, t.setNode(newPropertyError("O"+name, t.Properties["O"+name][0], ErrInvalidValue))
+	} else if !errors.Is(err, ErrMissingProperty) {
+		return rec, t.setNode(err)
+	}
+	return rec, nil
+}
+
+// spent computes the time spent on a move, given the clock state before
+// and after the move.  If the time cannot be determined, -1 is returned.
+func (ot *Overtime) spent(before float64, otBefore int, after float64, otAfter int) float64 {
+	if before < 0 || after < 0 {
+		return -1
+	}
+
+	switch ot.Kind {
+	case OvertimeFischer:
+		return before + ot.Increment - after
+	case OvertimeByoYomi:
+		if otAfter < 0 {
+			break
+		}
+		if otBefore < 0 {
+			// the player entered byo-yomi during this move
+			otBefore = ot.Periods
+		} else {
+			before = 0
+		}
+		return before + float64(otBefore-otAfter)*ot.PeriodTime + ot.PeriodTime - after
+	case OvertimeCanadian:
+		if otAfter < 0 {
+			break
+		}
+		if otBefore >= 0 && otAfter < otBefore && after <= before {
+			// still in the same period
+			return before - after
+		}
+		if otBefore >= 0 {
+			// a new period started
+			before = 0
+		}
+		return before + ot.PeriodTime - after
+	}
+
+	if after > before {
+		return -1
+	}
+	return before - after
+}
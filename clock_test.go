@@ -0,0 +1,142 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseOvertime(t *testing.T) {
+	cases := []struct {
+		in  string
+		out Overtime
+	}{
+		{"5x30 byo-yomi", Overtime{Kind: OvertimeByoYomi, Periods: 5, PeriodTime: 30}},
+		{"3x60 Byoyomi", Overtime{Kind: OvertimeByoYomi, Periods: 3, PeriodTime: 60}},
+		{"10 x 1 min", Overtime{Kind: OvertimeByoYomi, Periods: 10, PeriodTime: 60}},
+		{"25/600 Canadian", Overtime{Kind: OvertimeCanadian, Moves: 25, PeriodTime: 600}},
+		{"15/5min", Overtime{Kind: OvertimeCanadian, Moves: 15, PeriodTime: 300}},
+		{"Fischer 10", Overtime{Kind: OvertimeFischer, Increment: 10}},
+		{"+20 sec fischer", Overtime{Kind: OvertimeFischer, Increment: 20}},
+		{"none", Overtime{Kind: OvertimeNone}},
+		{"1 hour sudden death", Overtime{Kind: OvertimeUnknown}},
+		{"30", Overtime{Kind: OvertimeUnknown}},
+	}
+	for _, test := range cases {
+		ot := ParseOvertime(test.in)
+		test.out.Text = test.in
+		if ot != test.out {
+			t.Errorf("%q: expected %+v, got %+v", test.in, test.out, ot)
+		}
+	}
+}
+
+func TestTimeUsage(t *testing.T) {
+	tree := readTree(t, `(;TM[600]OT[3x30 byo-yomi]
+		;B[aa]BL[590];W[bb]WL[580]
+		(;B[cc]BL[5]OB[3];W[dd];B[ee]BL[20]OB[2];W[ff]WL[570])
+		(;B[]BL[0.5]))`)
+
+	usage, err := tree.TimeUsage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.MainTime != 600 || usage.Overtime.Kind != OvertimeByoYomi {
+		t.Errorf("wrong settings: %+v", usage)
+	}
+
+	type rec struct {
+		move     int
+		left     float64
+		overtime int
+		spent    float64
+	}
+	check := func(color string, records []TimeRecord, expected []rec) {
+		t.Helper()
+		if len(records) != len(expected) {
+			t.Fatalf("%s: expected %d records, got %d", color, len(expected), len(records))
+		}
+		for i, r := range records {
+			got := rec{r.MoveNumber, r.TimeLeft, r.OvertimeLeft, r.Spent}
+			if got != expected[i] {
+				t.Errorf("%s move %d: expected %+v, got %+v", color, i, expected[i], got)
+			}
+		}
+	}
+	check("black", usage.Black, []rec{
+		{1, 590, -1, 10},
+		{3, 5, 3, 615},
+		{5, 20, 2, 40},
+	})
+	check("white", usage.White, []rec{
+		{2, 580, -1, 20},
+		{4, -1, -1, -1},
+		{6, 570, -1, -1},
+	})
+	if usage.Black[1].Node != tree.Children[0].Children[0].Children[0] {
+		t.Error("wrong node")
+	}
+
+	usage, err = tree.TimeUsage([]int{0, 0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("black", usage.Black, []rec{
+		{1, 590, -1, 10},
+		{3, 0.5, -1, 589.5},
+	})
+
+	_, err = tree.TimeUsage([]int{0, 0, 2})
+	if !errors.Is(err, ErrNoSuchNode) {
+		t.Errorf("expected ErrNoSuchNode, got %v", err)
+	}
+}
+
+func TestTimeUsageInvalid(t *testing.T) {
+	tree := readTree(t, "(;TM[600];B[aa]BL[-3])")
+	_, err := tree.TimeUsage(nil)
+	var perr *PropertyError
+	if !errors.As(err, &perr) || perr.Name != "BL" || !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected invalid BL, got %v", err)
+	}
+}
+
+func TestOvertimeSpent(t *testing.T) {
+	canadian := ParseOvertime("10/300")
+	fischer := ParseOvertime("fischer 15")
+	cases := []struct {
+		ot       *Overtime
+		before   float64
+		otBefore int
+		after    float64
+		otAfter  int
+		spent    float64
+	}{
+		{&canadian, 30, -1, 280, 10, 50}, // entering overtime
+		{&canadian, 280, 10, 250, 9, 30}, // same period
+		{&canadian, 20, 1, 290, 10, 10},  // new period
+		{&fischer, 100, -1, 105, -1, 10},
+		{&fischer, -1, -1, 105, -1, -1},
+	}
+	for i, test := range cases {
+		spent := test.ot.spent(test.before, test.otBefore, test.after, test.otAfter)
+		if spent != test.spent {
+			t.Errorf("%d: expected %g, got %g", i, test.spent, spent)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+// A Cursor points to a node in a game tree, and keeps track of the path
+// from the root to this node.  This allows to navigate the tree in all
+// directions, and to edit the tree while keeping the cursor valid.
+// The tree must not be modified by other means while the cursor is
+// in use.
+type Cursor struct {
+	nodes []*Tree // the nodes from the root to the current node
+	path  Path    // path[i] is the index of nodes[i+1] in nodes[i].Children
+}
+
+// NewCursor returns a cursor which points to the root node t.
+func NewCursor(t *Tree) *Cursor {
+	return &Cursor{nodes: []*Tree{t}}
+}
+
+// Node returns the node the cursor points to.
+func (c *Cursor) Node() *Tree {
+	return c.nodes[len(c.nodes)-1]
+}
+
+// Depth returns the number of steps from the root to the current node.
+// The root node has depth 0.
+func (c *Cursor) Depth() int {
+	return len(c.path)
+}
+
+// Path returns the path from the root to the current node.
+func (c *Cursor) Path() Path {
+	return append(Path(nil), c.path...)
+}
+
+// MoveNumber returns the number of moves (B or W properties, including
+// passes) from the root to the current node.  If an MN property is
+// found on the way, the move number of that node is set to the value
+// given there, and counting continues from this value.
+func (c *Cursor) MoveNumber() int {
+	num := 0
+	for _, n := range c.nodes {
+		num = n.moveNumber(num)
+	}
+	return num
+}
+
+// moveNumber returns the move number of the node t, given the move number
+// of its parent.
+func (t *Tree) moveNumber(parent int) int {
+	_, b := t.Properties["B"]
+	_, w := t.Properties["W"]
+	if !b && !w {
+		return parent
+	}
+	if mn, err := t.GetNumber("MN"); err == nil {
+		return mn
+	}
+	return parent + 1
+}
+
+// Root moves the cursor to the root node.
+func (c *Cursor) Root() {
+	c.nodes = c.nodes[:1]
+	c.path = c.path[:0]
+}
+
+// Parent moves the cursor to the parent of the current node.  If the
+// cursor is at the root node, the cursor is not moved and false is
+// returned.
+func (c *Cursor) Parent() bool {
+	if len(c.path) == 0 {
+		return false
+	}
+	c.nodes = c.nodes[:len(c.nodes)-1]
+	c.path = c.path[:len(c.path)-1]
+	return true
+}
+
+// Child moves the cursor to the child with index i of the current node.
+// If there is no such child, the cursor is not moved and false is
+// returned.
+func (c *Cursor) Child(i int) bool {
+	n := c.Node()
+	if i < 0 || i >= len(n.Children) {
+		return false
+	}
+	c.nodes = append(c.nodes, n.Children[i])
+	c.path = append(c.path, i)
+	return true
+}
+
+// NextSibling moves the cursor to the next child of the parent node.  If
+// there is no such node, the cursor is not moved and false is returned.
+func (c *Cursor) NextSibling() bool {
+	return c.sibling(1)
+}
+
+// PrevSibling moves the cursor to the previous child of the parent node.
+// If there is no such node, the cursor is not moved and false is
+// returned.
+func (c *Cursor) PrevSibling() bool {
+	return c.sibling(-1)
+}
+
+func (c *Cursor) sibling(delta int) bool {
+	k := len(c.path)
+	if k == 0 {
+		return false
+	}
+	parent := c.nodes[k-1]
+	i := c.path[k-1] + delta
+	if i < 0 || i >= len(parent.Children) {
+		return false
+	}
+	c.nodes[k] = parent.Children[i]
+	c.path[k-1] = i
+	return true
+}
+
+// InsertChild inserts n as a new child of the current node, at index i.
+// Existing children with index i or higher are moved up by one.  The
+// cursor is not moved.  If i is out of range, the tree is not changed
+// and false is returned.
+func (c *Cursor) InsertChild(i int, n *Tree) bool {
+	parent := c.Node()
+	if i < 0 || i > len(parent.Children) {
+		return false
+	}
+	parent.Children = append(parent.Children, nil)
+	copy(parent.Children[i+1:], parent.Children[i:])
+	parent.Children[i] = n
+	return true
+}
+
+// AddChild appends n to the children of the current node.  The cursor is
+// not moved.
+func (c *Cursor) AddChild(n *Tree) {
+	parent := c.Node()
+	parent.Children = append(parent.Children, n)
+}
+
+// DeleteSubtree removes the current node, together with all of its
+// descendants, from the tree and moves the cursor to the parent
+// node.  The root node cannot be removed; in this case the tree is not
+// changed and false is returned.
+func (c *Cursor) DeleteSubtree() bool {
+	k := len(c.path)
+	if k == 0 {
+		return false
+	}
+	parent := c.nodes[k-1]
+	i := c.path[k-1]
+	copy(parent.Children[i:], parent.Children[i+1:])
+	parent.Children[len(parent.Children)-1] = nil
+	parent.Children = parent.Children[:len(parent.Children)-1]
+	c.Parent()
+	return true
+}
+
+// PromoteVariation moves the variation starting at the current node one
+// place up in the list of children of the parent node.  The cursor
+// keeps pointing to the same node.  If the node is already the first
+// child, or if the cursor is at the root, false is returned.
+func (c *Cursor) PromoteVariation() bool {
+	k := len(c.path)
+	if k == 0 || c.path[k-1] == 0 {
+		return false
+	}
+	parent := c.nodes[k-1]
+	i := c.path[k-1]
+	parent.Children[i-1], parent.Children[i] = parent.Children[i], parent.Children[i-1]
+	c.path[k-1] = i - 1
+	return true
+}
+
+// MakeMainVariation reorders the children of all nodes on the path from
+// the root to the current node, so that the current node becomes part
+// of the main variation.  The relative order of the other children is
+// preserved.  The cursor keeps pointing to the same node.
+func (c *Cursor) MakeMainVariation() {
+	for k, i := range c.path {
+		if i == 0 {
+			continue
+		}
+		children := c.nodes[k].Children
+		n := children[i]
+		copy(children[1:i+1], children[:i])
+		children[0] = n
+		c.path[k] = 0
+	}
+}
@@ -0,0 +1,134 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// moveOf returns the move stored in a node, for use in tests.
+func moveOf(n *Tree) string {
+	if v, ok := n.Properties["B"]; ok {
+		return "B" + v[0]
+	}
+	if v, ok := n.Properties["W"]; ok {
+		return "W" + v[0]
+	}
+	return ""
+}
+
+func TestCursorNavigation(t *testing.T) {
+	tree := readTree(t, "(;GM[1];B[aa](;W[bb];B[cc])(;W[dd]MN[10];B[ee])(;W[ff]))")
+	c := NewCursor(tree)
+
+	if c.Parent() || c.NextSibling() || c.PrevSibling() || c.Child(1) {
+		t.Error("invalid move from the root succeeded")
+	}
+	if c.Node() != tree || c.Depth() != 0 || c.MoveNumber() != 0 {
+		t.Error("wrong root state")
+	}
+
+	if !c.Child(0) || !c.Child(1) || moveOf(c.Node()) != "Wdd" {
+		t.Fatal("cannot move to second variation")
+	}
+	if c.MoveNumber() != 10 {
+		t.Errorf("wrong move number %d", c.MoveNumber())
+	}
+	if !c.Child(0) || c.MoveNumber() != 11 || c.Depth() != 3 {
+		t.Errorf("wrong state at %v: move %d", c.Path(), c.MoveNumber())
+	}
+	if d := cmp.Diff(Path{0, 1, 0}, c.Path()); d != "" {
+		t.Errorf("wrong path (-want +got):\n%s", d)
+	}
+	if c.NextSibling() {
+		t.Error("next sibling of only child")
+	}
+
+	c.Parent()
+	if !c.NextSibling() || moveOf(c.Node()) != "Wff" || c.NextSibling() {
+		t.Error("NextSibling failed")
+	}
+	if !c.PrevSibling() || !c.PrevSibling() || moveOf(c.Node()) != "Wbb" || c.PrevSibling() {
+		t.Error("PrevSibling failed")
+	}
+	if c.MoveNumber() != 2 {
+		t.Errorf("wrong move number %d", c.MoveNumber())
+	}
+
+	c.Root()
+	if c.Node() != tree || len(c.Path()) != 0 {
+		t.Error("Root failed")
+	}
+}
+
+func TestCursorEdit(t *testing.T) {
+	tree := readTree(t, "(;GM[1];B[aa](;W[bb];B[cc])(;W[dd];B[ee])(;W[ff]))")
+	c := NewCursor(tree)
+	c.Child(0)
+	b := c.Node()
+	moves := func() []string {
+		var res []string
+		for _, n := range b.Children {
+			res = append(res, moveOf(n))
+		}
+		return res
+	}
+
+	if !c.InsertChild(1, &Tree{Properties: Properties{"W": {"gg"}}}) {
+		t.Fatal("InsertChild failed")
+	}
+	if c.InsertChild(5, &Tree{}) {
+		t.Error("InsertChild out of range succeeded")
+	}
+	c.AddChild(&Tree{Properties: Properties{"W": {"hh"}}})
+	if d := cmp.Diff([]string{"Wbb", "Wgg", "Wdd", "Wff", "Whh"}, moves()); d != "" {
+		t.Errorf("wrong children (-want +got):\n%s", d)
+	}
+
+	c.Child(2)
+	if !c.PromoteVariation() || moveOf(c.Node()) != "Wdd" || c.Path()[1] != 1 {
+		t.Error("PromoteVariation failed")
+	}
+	if d := cmp.Diff([]string{"Wbb", "Wdd", "Wgg", "Wff", "Whh"}, moves()); d != "" {
+		t.Errorf("wrong children (-want +got):\n%s", d)
+	}
+
+	c.Parent()
+	c.Child(3)
+	c.MakeMainVariation()
+	if moveOf(c.Node()) != "Wff" || c.Path()[1] != 0 {
+		t.Error("MakeMainVariation failed")
+	}
+	if d := cmp.Diff([]string{"Wff", "Wbb", "Wdd", "Wgg", "Whh"}, moves()); d != "" {
+		t.Errorf("wrong children (-want +got):\n%s", d)
+	}
+
+	c.NextSibling()
+	if !c.DeleteSubtree() || c.Node() != b {
+		t.Error("DeleteSubtree failed")
+	}
+	if d := cmp.Diff([]string{"Wff", "Wdd", "Wgg", "Whh"}, moves()); d != "" {
+		t.Errorf("wrong children (-want +got):\n%s", d)
+	}
+
+	c.Root()
+	if c.DeleteSubtree() || c.PromoteVariation() {
+		t.Error("root modified")
+	}
+}
@@ -0,0 +1,185 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Date is a possibly partial calendar date, as used in the DT property.
+type Date struct {
+	Year  int
+	Month int // 1-12, or 0 if only the year is known
+	Day   int // 1-31, or 0 if only the year and month are known
+}
+
+// String returns the date in the format "YYYY-MM-DD", "YYYY-MM" or
+// "YYYY", depending on which parts of the date are known.
+func (d Date) String() string {
+	switch {
+	case d.Month == 0:
+		return fmt.Sprintf("%04d", d.Year)
+	case d.Day == 0:
+		return fmt.Sprintf("%04d-%02d", d.Year, d.Month)
+	default:
+		return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+	}
+}
+
+// Before reports whether d comes before e.  A partial date comes before
+// all complete dates within the same year or month.
+func (d Date) Before(e Date) bool {
+	if d.Year != e.Year {
+		return d.Year < e.Year
+	}
+	if d.Month != e.Month {
+		return d.Month < e.Month
+	}
+	return d.Day < e.Day
+}
+
+func (d Date) isValid() bool {
+	if d.Year < 0 || d.Year > 9999 || d.Month < 0 || d.Month > 12 || d.Day < 0 {
+		return false
+	}
+	if d.Month == 0 {
+		return d.Day == 0
+	}
+	// day 0 of the following month is the last day of d.Month
+	last := time.Date(d.Year, time.Month(d.Month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return d.Day <= last
+}
+
+// ParseDates decodes the value of a DT property.  The value is a comma
+// separated list of dates in the format "YYYY-MM-DD", "YYYY-MM" or
+// "YYYY".  As described in the SGF specification, a date may be
+// shortened to "MM-DD" or "DD" if the preceding date has the form
+// "YYYY-MM-DD" or "MM-DD", and to "MM" if the preceding date has the form
+// "YYYY-MM".  For example, "2002-01-30,31,02-01" denotes three dates.
+// If the value cannot be decoded, an error wrapping ErrInvalidValue is
+// returned.
+func ParseDates(val string) ([]Date, error) {
+	var res []Date
+	var prev Date
+	for i, part := range strings.Split(val, ",") {
+		part = strings.TrimSpace(part)
+		fields := strings.Split(part, "-")
+		nums := make([]int, len(fields))
+		ok := len(fields) <= 3
+		for j, f := range fields {
+			// all fields have two digits, except for a leading year
+			if !(len(f) == 2 || j == 0 && len(f) == 4) || strings.Trim(f, "0123456789") != "" {
+				ok = false
+				break
+			}
+			nums[j], _ = strconv.Atoi(f)
+			if len(f) == 2 && nums[j] == 0 {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w %q for DT", ErrInvalidValue, val)
+		}
+
+		var d Date
+		switch {
+		case len(fields[0]) == 4:
+			d.Year = nums[0]
+			if len(nums) > 1 {
+				d.Month = nums[1]
+			}
+			if len(nums) > 2 {
+				d.Day = nums[2]
+			}
+		case i == 0 || len(nums) > 2:
+			ok = false
+		case len(nums) == 2 && prev.Day != 0: // MM-DD
+			d = Date{prev.Year, nums[0], nums[1]}
+		case len(nums) == 1 && prev.Day != 0: // DD
+			d = Date{prev.Year, prev.Month, nums[0]}
+		case len(nums) == 1 && prev.Month != 0: // MM
+			d = Date{prev.Year, nums[0], 0}
+		default:
+			ok = false
+		}
+		if !ok || !d.isValid() {
+			return nil, fmt.Errorf("%w %q for DT", ErrInvalidValue, val)
+		}
+		res = append(res, d)
+		prev = d
+	}
+	return res, nil
+}
+
+// FormatDates encodes a list of dates in the compact form used for the DT
+// property, using the shortcuts described for ParseDates wherever
+// possible.  If a date is invalid, an error wrapping ErrInvalidValue is
+// returned.
+func FormatDates(dates []Date) (string, error) {
+	parts := make([]string, len(dates))
+	var prev Date
+	for i, d := range dates {
+		if !d.isValid() {
+			return "", fmt.Errorf("%w %q for DT", ErrInvalidValue, d.String())
+		}
+		switch {
+		case i == 0 || d.Year != prev.Year || d.Month == 0:
+			parts[i] = d.String()
+		case d.Day != 0 && prev.Day != 0 && d.Month == prev.Month:
+			parts[i] = fmt.Sprintf("%02d", d.Day)
+		case d.Day != 0 && prev.Day != 0:
+			parts[i] = fmt.Sprintf("%02d-%02d", d.Month, d.Day)
+		case d.Day == 0 && prev.Day == 0 && prev.Month != 0:
+			parts[i] = fmt.Sprintf("%02d", d.Month)
+		default:
+			parts[i] = d.String()
+		}
+		prev = d
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// GetDates returns the dates given by the DT property of t.  If the
+// property is missing, has more than one value, or cannot be decoded by
+// ParseDates, an error is returned.
+func (t *Tree) GetDates() ([]Date, error) {
+	val, err := t.GetSimpleText("DT")
+	if err != nil {
+		return nil, t.setNode(err)
+	}
+	res, err := ParseDates(val)
+	if err != nil {
+		return nil, t.setNode(newPropertyError("DT", val, ErrInvalidValue))
+	}
+	return res, nil
+}
+
+// SetDates sets the DT property of t to the compact form of the given
+// dates, as produced by FormatDates.  If a date is invalid, an error is
+// returned and t is not modified.
+func (t *Tree) SetDates(dates []Date) error {
+	val, err := FormatDates(dates)
+	if err != nil {
+		return err
+	}
+	t.SetSimpleText("DT", val)
+	return nil
+}
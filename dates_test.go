@@ -0,0 +1,119 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseDates(t *testing.T) {
+	cases := []struct {
+		in    string
+		dates []Date
+	}{
+		{"1996", []Date{{1996, 0, 0}}},
+		{"1996-05-27", []Date{{1996, 5, 27}}},
+		{"1996-05,06", []Date{{1996, 5, 0}, {1996, 6, 0}}},
+		{"1996-12-27,28", []Date{{1996, 12, 27}, {1996, 12, 28}}},
+		{"2002-01-30,31,02-01", []Date{{2002, 1, 30}, {2002, 1, 31}, {2002, 2, 1}}},
+		{"1996-05-06,07-08,09", []Date{{1996, 5, 6}, {1996, 7, 8}, {1996, 7, 9}}},
+		{"1976,1997-12-31,1998-01", []Date{{1976, 0, 0}, {1997, 12, 31}, {1998, 1, 0}}},
+		{"2000-02-29", []Date{{2000, 2, 29}}},
+	}
+	for _, test := range cases {
+		dates, err := ParseDates(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if d := cmp.Diff(test.dates, dates); d != "" {
+			t.Errorf("%q: wrong dates (-want +got):\n%s", test.in, d)
+		}
+		if s, err := FormatDates(dates); err != nil || s != test.in {
+			t.Errorf("%q: formatted as %q, %v", test.in, s, err)
+		}
+	}
+}
+
+func TestParseDatesInvalid(t *testing.T) {
+	cases := []string{
+		"",
+		"96-05-27",
+		"05-27",
+		"1996,05",
+		"1996-05,06-07",
+		"1996-13",
+		"1996-00",
+		"1996-05-27,00",
+		"1999-02-29",
+		"1996-5-27",
+		"1996-05-27-01",
+		"1996-+5",
+		"May 1996",
+	}
+	for _, in := range cases {
+		_, err := ParseDates(in)
+		if !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("%q: expected ErrInvalidValue, got %v", in, err)
+		}
+	}
+}
+
+func TestFormatDates(t *testing.T) {
+	dates := []Date{{1996, 5, 0}, {1996, 5, 3}, {1996, 6, 0}, {1997, 6, 0}, {1997, 0, 0}}
+	if s, err := FormatDates(dates); err != nil || s != "1996-05,1996-05-03,1996-06,1997-06,1997" {
+		t.Errorf("wrong format %q, %v", s, err)
+	}
+
+	for _, d := range []Date{{1996, 13, 0}, {1999, 2, 29}, {1996, 0, 1}, {-1, 0, 0}} {
+		_, err := FormatDates([]Date{{1996, 1, 1}, d})
+		if !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("%v: expected ErrInvalidValue, got %v", d, err)
+		}
+	}
+}
+
+func TestDateBefore(t *testing.T) {
+	dates := []Date{{1997, 1, 1}, {1996, 5, 0}, {1996, 0, 0}, {1996, 5, 2}, {1996, 4, 30}}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	expected := []Date{{1996, 0, 0}, {1996, 4, 30}, {1996, 5, 0}, {1996, 5, 2}, {1997, 1, 1}}
+	if d := cmp.Diff(expected, dates); d != "" {
+		t.Errorf("wrong order (-want +got):\n%s", d)
+	}
+}
+
+func TestTreeDates(t *testing.T) {
+	tree := readTree(t, "(;DT[2002-01-30,31,02-01])")
+	dates, err := tree.GetDates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dates) != 3 {
+		t.Errorf("wrong dates %v", dates)
+	}
+
+	if err := tree.SetDates([]Date{{2023, 7, 1}, {2023, 7, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if val := tree.Properties["DT"]; len(val) != 1 || val[0] != "2023-07-01,02" {
+		t.Errorf("wrong DT property %q", val)
+	}
+}
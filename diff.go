@@ -0,0 +1,251 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// ChangeKind describes the type of a change found by Diff.
+type ChangeKind int
+
+// These are the possible values for ChangeKind.
+const (
+	// NodeChanged means that the properties of a node were changed.
+	NodeChanged ChangeKind = iota
+
+	// VariationAdded means that a subtree was added to the tree.
+	VariationAdded
+
+	// VariationRemoved means that a subtree was removed from the tree.
+	VariationRemoved
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case NodeChanged:
+		return "changed"
+	case VariationAdded:
+		return "added"
+	case VariationRemoved:
+		return "removed"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (k *ChangeKind) UnmarshalText(text []byte) error {
+	for _, kind := range []ChangeKind{NodeChanged, VariationAdded, VariationRemoved} {
+		if string(text) == kind.String() {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid change kind %q", text)
+}
+
+// A Change describes a difference between two game trees.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+
+	// Path identifies the node.  For removed variations, this is the
+	// path of the first node of the variation in the old tree; in all
+	// other cases it is the path in the new tree.
+	Path Path `json:"path"`
+
+	// Nodes is the number of nodes in an added or removed variation.
+	Nodes int `json:"nodes,omitempty"`
+
+	// Properties lists the changed properties of a node, for NodeChanged.
+	// For added and removed variations, this lists the properties of the
+	// first node of the variation.
+	Properties []PropertyChange `json:"properties,omitempty"`
+}
+
+// A PropertyChange describes a change of the (raw) values of a property.
+// Old is nil for added properties, and New is nil for removed properties.
+type PropertyChange struct {
+	Name string   `json:"name"`
+	Old  []string `json:"old,omitempty"`
+	New  []string `json:"new,omitempty"`
+}
+
+// A TreeDiff lists the differences between two game trees.
+type TreeDiff struct {
+	Changes []Change `json:"changes"`
+}
+
+// Diff compares the game trees a and b, and returns the changes which
+// turn a into b.  Nodes are compared starting at the roots.  The
+// children of corresponding nodes are matched by their moves, in order,
+// so that reordering or inserting variations does not affect the
+// matching of the remaining variations; a change in the order of the
+// variations alone is not reported.  Children of b without a
+// counterpart in a are reported as added variations, children of a
+// without a counterpart in b as removed variations, and differing
+// property values of matched nodes as changed nodes.  The changes are
+// listed in the order found by a depth-first traversal.
+func Diff(a, b *Tree) *TreeDiff {
+	res := &TreeDiff{Changes: []Change{}}
+
+	type task struct {
+		a, b         *Tree
+		aPath, bPath Path
+	}
+	stack := []task{{a: a, b: b}}
+	for len(stack) > 0 {
+		tk := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if props := diffProperties(tk.a.Properties, tk.b.Properties); props != nil {
+			res.Changes = append(res.Changes, Change{
+				Kind:       NodeChanged,
+				Path:       tk.bPath,
+				Properties: props,
+			})
+		}
+
+		matched := make([]bool, len(tk.a.Children))
+		var next []task
+	childLoop:
+		for j, bc := range tk.b.Children {
+			key := bc.diffKey()
+			for i, ac := range tk.a.Children {
+				if !matched[i] && ac.diffKey() == key {
+					matched[i] = true
+					next = append(next, task{ac, bc, tk.aPath.child(i), tk.bPath.child(j)})
+					continue childLoop
+				}
+			}
+			res.Changes = append(res.Changes, subtreeChange(VariationAdded, bc, tk.bPath.child(j)))
+		}
+		for i, ac := range tk.a.Children {
+			if !matched[i] {
+				res.Changes = append(res.Changes, subtreeChange(VariationRemoved, ac, tk.aPath.child(i)))
+			}
+		}
+
+		for i := len(next) - 1; i >= 0; i-- {
+			stack = append(stack, next[i])
+		}
+	}
+	return res
+}
+
+// diffKey returns the (raw) move of a node, which is used to match nodes
+// in Diff.
+func (t *Tree) diffKey() string {
+	return "B" + strings.Join(t.Properties["B"], "][") +
+		";W" + strings.Join(t.Properties["W"], "][")
+}
+
+// subtreeChange describes an added or removed variation.
+func subtreeChange(kind ChangeKind, t *Tree, path Path) Change {
+	nodes := 0
+	_ = t.Walk(func(*Tree, int, Path, int) error {
+		nodes++
+		return nil
+	})
+	var props []PropertyChange
+	if kind == VariationAdded {
+		props = diffProperties(nil, t.Properties)
+	} else {
+		props = diffProperties(t.Properties, nil)
+	}
+	return Change{Kind: kind, Path: path, Nodes: nodes, Properties: props}
+}
+
+// diffProperties lists the differences between the properties of two
+// nodes, sorted by name.  If there are no differences, nil is returned.
+func diffProperties(a, b Properties) []PropertyChange {
+	names := maps.Keys(a)
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var res []PropertyChange
+	for _, name := range names {
+		before, after := a[name], b[name]
+		if !slices.Equal(before, after) {
+			res = append(res, PropertyChange{Name: name, Old: before, New: after})
+		}
+	}
+	return res
+}
+
+// String returns a textual description of the changes, with one line
+// per change.
+func (d *TreeDiff) String() string {
+	var sb strings.Builder
+	for _, c := range d.Changes {
+		switch c.Kind {
+		case NodeChanged:
+			fmt.Fprintf(&sb, "node %s changed:", c.Path)
+			for _, p := range c.Properties {
+				switch {
+				case p.Old == nil:
+					fmt.Fprintf(&sb, " +%s", formatProperty(p.Name, p.New))
+				case p.New == nil:
+					fmt.Fprintf(&sb, " -%s", formatProperty(p.Name, p.Old))
+				default:
+					fmt.Fprintf(&sb, " %s->%s",
+						formatProperty(p.Name, p.Old), formatProperty("", p.New))
+				}
+			}
+		default:
+			s := "s"
+			if c.Nodes == 1 {
+				s = ""
+			}
+			fmt.Fprintf(&sb, "variation %s at %s (%d node%s):", c.Kind, c.Path, c.Nodes, s)
+			for _, p := range c.Properties {
+				vals := p.New
+				if vals == nil {
+					vals = p.Old
+				}
+				sb.WriteString(" " + formatProperty(p.Name, vals))
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// JSON returns a machine-readable representation of the changes.
+func (d *TreeDiff) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// formatProperty formats a property in SGF syntax.
+func formatProperty(name string, values []string) string {
+	return name + "[" + strings.Join(values, "][") + "]"
+}
@@ -0,0 +1,102 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestDiff(t *testing.T) {
+	a := readTree(t, "(;SZ[9]KM[6.5];B[ee]C[good](;W[cc];B[gg])(;W[dd]))")
+	b := readTree(t, "(;SZ[9]KM[7]PB[x];B[ee](;W[ff])(;W[cc]TR[aa];B[gg]))")
+
+	d := Diff(a, b)
+	expected := `node 0 changed: KM[6.5]->[7] +PB[x]
+node 1 changed: -C[good]
+variation added at 2 (1 node): W[ff]
+variation removed at 1.1 (1 node): W[dd]
+node 1.1 changed: +TR[aa]
+`
+	if s := d.String(); s != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, s)
+	}
+
+	data, err := d.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Changes []struct {
+			Kind       string
+			Path       string
+			Nodes      int
+			Properties []PropertyChange
+		}
+	}
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Changes) != 5 {
+		t.Fatalf("wrong number of changes in %s", data)
+	}
+	c := decoded.Changes[3]
+	if c.Kind != "removed" || c.Path != "1.1" || c.Nodes != 1 ||
+		len(c.Properties) != 1 || c.Properties[0].Old[0] != "dd" {
+		t.Errorf("wrong JSON change %+v", c)
+	}
+	c = decoded.Changes[0]
+	if c.Kind != "changed" || c.Properties[0].Name != "KM" || c.Properties[1].Old != nil {
+		t.Errorf("wrong JSON change %+v", c)
+	}
+}
+
+func TestDiffJSONRoundTrip(t *testing.T) {
+	a := readTree(t, "(;SZ[9]KM[6.5];B[ee]C[good](;W[cc];B[gg])(;W[dd]))")
+	b := readTree(t, "(;SZ[9]KM[7]PB[x];B[ee](;W[ff])(;W[cc]TR[aa];B[gg]))")
+	d := Diff(a, b)
+
+	data, err := d.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &TreeDiff{}
+	err = json.Unmarshal(data, decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(d, decoded, cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("JSON round trip failed (-want +got):\n%s", diff)
+	}
+
+	err = json.Unmarshal([]byte(`{"changes":[{"kind":"moved","path":"1"}]}`), decoded)
+	if err == nil {
+		t.Error("invalid change kind accepted")
+	}
+}
+
+func TestDiffEqual(t *testing.T) {
+	a := readTree(t, "(;SZ[9];B[ee](;W[cc])(;W[dd]))")
+	b := readTree(t, "(;SZ[9];B[ee](;W[dd])(;W[cc]))")
+	if d := Diff(a, b); len(d.Changes) != 0 {
+		t.Errorf("unexpected changes:\n%s", d)
+	}
+}
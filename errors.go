@@ -0,0 +1,77 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// These errors describe the reason why a property could not be decoded.
+// They are wrapped in a *PropertyError and can be tested for using
+// errors.Is.
+var (
+	ErrMissingProperty  = errors.New("missing property")
+	ErrInvalidIdent     = errors.New("invalid property identifier")
+	ErrValueCount       = errors.New("wrong number of property values")
+	ErrInvalidValue     = errors.New("invalid property value")
+	ErrOutOfTurn        = errors.New("move played out of turn")
+	ErrConflictingMoves = errors.New("both B and W are set")
+)
+
+// A PropertyError records a problem with a property of a game tree node.
+type PropertyError struct {
+	Name  string // the property identifier
+	Value string // the offending value, if any
+	Node  *Tree  // the node which holds the property, if known
+
+	// Line, Column and Offset give the position of the node in the SGF
+	// input, in the same format as for ParseError.  They are filled in by
+	// Decoder.Locate.  If the position is not known, Line is 0.
+	Line, Column, Offset int
+
+	Err error // the reason for the problem, e.g. ErrInvalidValue
+}
+
+func newPropertyError(name string, value string, err error) *PropertyError {
+	return &PropertyError{Name: name, Value: value, Err: err}
+}
+
+func (e *PropertyError) Error() string {
+	msg := fmt.Sprintf("property %s: %v", e.Name, e.Err)
+	if e.Value != "" {
+		msg += fmt.Sprintf(" %q", e.Value)
+	}
+	if e.Line > 0 {
+		msg = fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, msg)
+	}
+	return msg
+}
+
+func (e *PropertyError) Unwrap() error {
+	return e.Err
+}
+
+// setNode records t as the node of err, if err is a *PropertyError
+// without node information.
+func (t *Tree) setNode(err error) error {
+	e, ok := err.(*PropertyError)
+	if ok && e.Node == nil {
+		e.Node = t
+	}
+	return err
+}
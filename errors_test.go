@@ -0,0 +1,82 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPropertyErrors(t *testing.T) {
+	n := Properties{
+		"A": []string{"1", "2"},
+		"B": []string{"x"},
+		"C": []string{""},
+		"D": []string{"+7"},
+	}
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{"A", ErrValueCount},
+		{"B", ErrInvalidValue},
+		{"C", ErrInvalidValue},
+		{"D", nil},
+		{"E", ErrMissingProperty},
+	}
+	for _, test := range cases {
+		_, err := n.GetNumber(test.name)
+		if !errors.Is(err, test.err) {
+			t.Errorf("GetNumber(%q): expected %v, got %v", test.name, test.err, err)
+		}
+		if err == nil {
+			continue
+		}
+		var pe *PropertyError
+		if !errors.As(err, &pe) || pe.Name != test.name {
+			t.Errorf("GetNumber(%q): expected PropertyError, got %v", test.name, err)
+		}
+	}
+
+	val, err := n.GetNumberDefault("E", 5)
+	if err != nil || val != 5 {
+		t.Errorf("GetNumberDefault: expected 5, got %d, %v", val, err)
+	}
+}
+
+func TestMoveErrors(t *testing.T) {
+	in := "(;SZ[9]\n;B[aa]\n;B[bb])"
+	d := NewDecoder(strings.NewReader(in), nil)
+	tree, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tree.MainVariationMoves()
+	err = d.Locate(err)
+	if !errors.Is(err, ErrOutOfTurn) {
+		t.Fatalf("expected ErrOutOfTurn, got %v", err)
+	}
+	var pe *PropertyError
+	if !errors.As(err, &pe) {
+		t.Fatalf("expected PropertyError, got %v", err)
+	}
+	if pe.Name != "B" || pe.Value != "bb" || pe.Node != tree.Children[0].Children[0] ||
+		pe.Line != 3 || pe.Column != 1 {
+		t.Errorf("wrong error details: %#v", pe)
+	}
+}
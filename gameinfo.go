@@ -0,0 +1,253 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+)
+
+// GameInfo collects the game-info properties of a game.  String fields
+// are empty, and pointer fields are nil, if the corresponding property is
+// missing.
+type GameInfo struct {
+	BlackPlayer string // PB
+	WhitePlayer string // PW
+	BlackRank   string // BR
+	WhiteRank   string // WR
+	BlackTeam   string // BT
+	WhiteTeam   string // WT
+
+	Event    string // EV
+	Round    string // RO
+	Place    string // PC
+	GameName string // GN
+	Opening  string // ON
+
+	Komi      *float64 // KM
+	Handicap  *int     // HA
+	TimeLimit *float64 // TM, in seconds
+	Overtime  string   // OT
+	Rules     string   // RU, see RulesByName
+
+	Result *Result // RE, or nil if the result is not given
+	Dates  []Date  // DT
+
+	GameComment string // GC, a multi-line text
+	Source      string // SO
+	User        string // US
+	Annotator   string // AN
+	Copyright   string // CP
+}
+
+// gameInfoProps lists the identifiers of all game-info properties.
+var gameInfoProps = []string{
+	"PB", "PW", "BR", "WR", "BT", "WT", "EV", "RO", "PC", "GN", "ON",
+	"KM", "HA", "TM", "OT", "RU", "RE", "DT", "GC", "SO", "US", "AN", "CP",
+}
+
+// simpleTextFields returns pointers to the fields of info which are
+// stored as SimpleText properties.
+func (info *GameInfo) simpleTextFields() []struct {
+	name string
+	val  *string
+} {
+	return []struct {
+		name string
+		val  *string
+	}{
+		{"PB", &info.BlackPlayer},
+		{"PW", &info.WhitePlayer},
+		{"BR", &info.BlackRank},
+		{"WR", &info.WhiteRank},
+		{"BT", &info.BlackTeam},
+		{"WT", &info.WhiteTeam},
+		{"EV", &info.Event},
+		{"RO", &info.Round},
+		{"PC", &info.Place},
+		{"GN", &info.GameName},
+		{"ON", &info.Opening},
+		{"OT", &info.Overtime},
+		{"RU", &info.Rules},
+		{"SO", &info.Source},
+		{"US", &info.User},
+		{"AN", &info.Annotator},
+		{"CP", &info.Copyright},
+	}
+}
+
+// GameInfoNode returns the node which holds the game-info properties.
+// This is the first node on the main variation, starting at t, which
+// contains at least one game-info property.  If there is no such node,
+// nil is returned.
+func (t *Tree) GameInfoNode() *Tree {
+	for n := t; ; n = n.Children[0] {
+		for _, name := range gameInfoProps {
+			if _, ok := n.Properties[name]; ok {
+				return n
+			}
+		}
+		if len(n.Children) == 0 {
+			return nil
+		}
+	}
+}
+
+// GameInfo decodes the game-info properties of the game with root t,
+// as found by GameInfoNode.  Properties which cannot be decoded are left
+// at their zero value in the result, and the problems are reported in
+// the returned list of errors.
+func (t *Tree) GameInfo() (*GameInfo, []*PropertyError) {
+	info := &GameInfo{}
+	n := t.GameInfoNode()
+	if n == nil {
+		return info, nil
+	}
+
+	var errs []*PropertyError
+	check := func(err error) {
+		var perr *PropertyError
+		if errors.As(n.setNode(err), &perr) {
+			errs = append(errs, perr)
+		}
+	}
+
+	for _, f := range info.simpleTextFields() {
+		val, err := n.GetSimpleTextDefault(f.name, "")
+		check(err)
+		*f.val = val
+	}
+	val, err := n.GetTextDefault("GC", "")
+	check(err)
+	info.GameComment = val
+
+	if _, ok := n.Properties["KM"]; ok {
+		km, err := n.GetReal("KM")
+		check(err)
+		if err == nil {
+			info.Komi = &km
+		}
+	}
+	if _, ok := n.Properties["HA"]; ok {
+		ha, err := n.GetNumber("HA")
+		check(err)
+		if err == nil {
+			info.Handicap = &ha
+		}
+	}
+	if _, ok := n.Properties["TM"]; ok {
+		tm, err := n.GetReal("TM")
+		check(err)
+		if err == nil {
+			info.TimeLimit = &tm
+		}
+	}
+
+	if _, ok := n.Properties["RE"]; ok {
+		res, err := n.GetResult()
+		check(err)
+		if err == nil {
+			info.Result = &res
+		}
+	}
+	if _, ok := n.Properties["DT"]; ok {
+		info.Dates, err = n.GetDates()
+		check(err)
+	}
+
+	return info, errs
+}
+
+// SetGameInfo stores info in the game-info properties of the game with
+// root t.  The properties are written to the node found by GameInfoNode,
+// or to t if there is no such node.  Properties for empty string fields,
+// nil pointer fields and empty Dates are removed, except for properties
+// whose existing values cannot be decoded: since GameInfo leaves the
+// corresponding fields empty, these are kept unchanged, so that a round
+// trip through GameInfo and SetGameInfo does not lose them.  If info
+// contains an invalid date, an error is returned and no properties are
+// modified.
+func (t *Tree) SetGameInfo(info *GameInfo) error {
+	var dt string
+	if len(info.Dates) > 0 {
+		var err error
+		dt, err = FormatDates(info.Dates)
+		if err != nil {
+			return err
+		}
+	}
+
+	n := t.GameInfoNode()
+	if n == nil {
+		n = t
+	}
+	if n.Properties == nil {
+		n.Properties = Properties{}
+	}
+
+	// Properties which cannot be decoded are not removed.
+	_, errs := t.GameInfo()
+	invalid := make(map[string]bool)
+	for _, err := range errs {
+		invalid[err.Name] = true
+	}
+	remove := func(name string) {
+		if !invalid[name] {
+			delete(n.Properties, name)
+		}
+	}
+
+	for _, f := range info.simpleTextFields() {
+		if *f.val == "" {
+			remove(f.name)
+		} else {
+			n.SetSimpleText(f.name, *f.val)
+		}
+	}
+	if info.GameComment == "" {
+		remove("GC")
+	} else {
+		n.SetText("GC", info.GameComment)
+	}
+
+	if info.Komi == nil {
+		remove("KM")
+	} else {
+		n.SetReal("KM", *info.Komi)
+	}
+	if info.Handicap == nil {
+		remove("HA")
+	} else {
+		n.SetNumber("HA", *info.Handicap)
+	}
+	if info.TimeLimit == nil {
+		remove("TM")
+	} else {
+		n.SetReal("TM", *info.TimeLimit)
+	}
+
+	if info.Result == nil {
+		remove("RE")
+	} else {
+		n.SetResult(*info.Result)
+	}
+	if len(info.Dates) == 0 {
+		remove("DT")
+	} else {
+		n.SetSimpleText("DT", dt)
+	}
+	return nil
+}
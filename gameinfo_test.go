@@ -0,0 +1,175 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGameInfo(t *testing.T) {
+	tree := readTree(t, `(;FF[4]SZ[19];B[pd]PB[Honinbo Shusaku]BR[4d]PW[Gennan Inseki]
+		WR[8d]EV[Castle game]RO[1]PC[Edo]KM[0]HA[0]TM[3600]OT[none]RU[Japanese]
+		RE[B+2]DT[1846-09-11,12]GN[Ear-reddening game]GC[famous
+game]SO[GoGoD];W[dp])`)
+
+	info, errs := tree.GameInfo()
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	zero, zeroKomi, timeLimit := 0, 0.0, 3600.0
+	expected := &GameInfo{
+		BlackPlayer: "Honinbo Shusaku",
+		WhitePlayer: "Gennan Inseki",
+		BlackRank:   "4d",
+		WhiteRank:   "8d",
+		Event:       "Castle game",
+		Round:       "1",
+		Place:       "Edo",
+		GameName:    "Ear-reddening game",
+		Komi:        &zeroKomi,
+		Handicap:    &zero,
+		TimeLimit:   &timeLimit,
+		Overtime:    "none",
+		Rules:       "Japanese",
+		Result:      &Result{Kind: ResultWin, Winner: Black, Reason: ReasonScore, Margin: 2},
+		Dates:       []Date{{1846, 9, 11}, {1846, 9, 12}},
+		GameComment: "famous\ngame",
+		Source:      "GoGoD",
+	}
+	if d := cmp.Diff(expected, info); d != "" {
+		t.Errorf("wrong game info (-want +got):\n%s", d)
+	}
+
+	// the game info must be written back to the second node
+	komi := 6.5
+	info.Komi = &komi
+	info.Source = ""
+	if err := tree.SetGameInfo(info); err != nil {
+		t.Fatal(err)
+	}
+	if tree.GameInfoNode() != tree.Children[0] {
+		t.Error("game-info node has moved")
+	}
+	props := tree.Children[0].Properties
+	if props["KM"][0] != "6.5" || props["PB"][0] != "Honinbo Shusaku" || props["B"][0] != "pd" {
+		t.Errorf("wrong properties %v", props)
+	}
+	if _, ok := props["SO"]; ok {
+		t.Error("SO not removed")
+	}
+	info2, errs := tree.GameInfo()
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if d := cmp.Diff(info, info2); d != "" {
+		t.Errorf("round trip failed (-want +got):\n%s", d)
+	}
+}
+
+func TestGameInfoErrors(t *testing.T) {
+	tree := readTree(t, "(;PB[Black]KM[six]HA[2]RE[B+lots]DT[yesterday]PW[a][b])")
+	info, errs := tree.GameInfo()
+	if info.BlackPlayer != "Black" || info.Handicap == nil || *info.Handicap != 2 {
+		t.Errorf("valid fields not decoded: %+v", info)
+	}
+	if info.Result != nil || info.Dates != nil || info.Komi != nil {
+		t.Errorf("invalid fields decoded: %+v", info)
+	}
+
+	names := map[string]error{}
+	for _, err := range errs {
+		names[err.Name] = err.Err
+		if err.Node != tree {
+			t.Errorf("%s: missing node", err.Name)
+		}
+	}
+	expected := map[string]error{
+		"KM": ErrInvalidValue,
+		"RE": ErrInvalidValue,
+		"DT": ErrInvalidValue,
+		"PW": ErrValueCount,
+	}
+	if len(names) != len(expected) {
+		t.Errorf("wrong errors %v", errs)
+	}
+	for name, err := range expected {
+		if !errors.Is(names[name], err) {
+			t.Errorf("%s: expected %v, got %v", name, err, names[name])
+		}
+	}
+}
+
+func TestGameInfoKeepInvalid(t *testing.T) {
+	tree := readTree(t, "(;PB[Black]KM[six]HA[2]RE[B+lots]DT[yesterday]PW[a][b])")
+	info, _ := tree.GameInfo()
+	info.Handicap = nil
+	if err := tree.SetGameInfo(info); err != nil {
+		t.Fatal(err)
+	}
+	expected := Properties{
+		"PB": {"Black"},
+		"KM": {"six"},
+		"RE": {"B+lots"},
+		"DT": {"yesterday"},
+		"PW": {"a", "b"},
+	}
+	if d := cmp.Diff(expected, tree.Properties); d != "" {
+		t.Errorf("wrong properties (-want +got):\n%s", d)
+	}
+}
+
+func TestSetGameInfoEmpty(t *testing.T) {
+	tree := &Tree{}
+	komi := 0.0
+	err := tree.SetGameInfo(&GameInfo{Event: "test", Komi: &komi})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Properties{"EV": {"test"}, "KM": {"0"}}
+	if d := cmp.Diff(expected, tree.Properties); d != "" {
+		t.Errorf("wrong properties (-want +got):\n%s", d)
+	}
+}
+
+func TestGameInfoMissing(t *testing.T) {
+	tree := readTree(t, "(;SZ[9];B[ee])")
+	if tree.GameInfoNode() != nil {
+		t.Error("unexpected game-info node")
+	}
+	info, errs := tree.GameInfo()
+	if len(errs) > 0 || !cmp.Equal(info, &GameInfo{}) {
+		t.Errorf("unexpected game info %+v, %v", info, errs)
+	}
+
+	if err := tree.SetGameInfo(&GameInfo{Event: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if tree.GameInfoNode() != tree {
+		t.Error("game info not stored in the root")
+	}
+
+	err := tree.SetGameInfo(&GameInfo{Event: "other", Dates: []Date{{2023, 2, 30}}})
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected ErrInvalidValue, got %v", err)
+	}
+	if ev := tree.Properties["EV"]; len(ev) != 1 || ev[0] != "test" {
+		t.Errorf("properties modified after error: EV%q", ev)
+	}
+}
@@ -0,0 +1,232 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// ConflictPolicy describes how Merge combines properties which have
+// different values in two merged nodes.
+type ConflictPolicy int
+
+// These are the supported conflict policies.
+const (
+	// KeepFirst keeps the values from the tree which comes first in the
+	// argument list of Merge.
+	KeepFirst ConflictPolicy = iota
+
+	// KeepLast keeps the values from the tree which comes last in the
+	// argument list of Merge.
+	KeepLast
+
+	// Combine concatenates the texts of comment properties (C and GC),
+	// separated by an empty line, and forms the union of the values of
+	// markup properties (AR, CR, DD, LB, LN, MA, SL, SQ, TR, TB, TW and
+	// VW).  For all other properties, the values of the first tree are
+	// kept.
+	Combine
+)
+
+// MergeOptions controls how game trees are merged.
+type MergeOptions struct {
+	// MatchSetup indicates whether nodes must have the same setup
+	// properties (AB, AW, AE and PL) to be merged.  Nodes must always
+	// have the same move to be merged.
+	MatchSetup bool
+
+	// Policy gives how conflicting property values are combined.
+	Policy ConflictPolicy
+
+	// Resolve, if non-nil, is called for every property which has
+	// different values a and b in two merged nodes, where a comes from
+	// the earlier tree.  The returned values are used in the merged
+	// node; if no values are returned, the property is removed from the
+	// merged node.  If Resolve is set, Policy is ignored.
+	Resolve func(name string, a, b []string) []string
+}
+
+// Merge merges several game trees of the same game into one tree, using
+// default options.  See MergeWithOptions for details.
+func Merge(trees ...*Tree) (*Tree, error) {
+	return MergeWithOptions(nil, trees...)
+}
+
+// MergeWithOptions merges several game trees of the same game into one
+// tree.  The root nodes of all trees are merged.  Below the root, two
+// nodes with the same parent are merged if they contain the same move
+// (and, if opt.MatchSetup is set, the same setup properties); otherwise
+// they become separate variations.  Variations appear in the order in
+// which they are first found.  If opt is nil, default options are used.
+//
+// The given trees are not modified.  An error is returned if the trees
+// have different board sizes.
+func MergeWithOptions(opt *MergeOptions, trees ...*Tree) (*Tree, error) {
+	if opt == nil {
+		opt = &MergeOptions{}
+	}
+	if len(trees) == 0 {
+		return nil, nil
+	}
+
+	sz, err := trees[0].GetBoardSize()
+	if err != nil {
+		return nil, err
+	}
+	res := trees[0].clone()
+	for _, t := range trees[1:] {
+		sz2, err := t.GetBoardSize()
+		if err != nil {
+			return nil, err
+		}
+		if sz2 != sz {
+			return nil, fmt.Errorf("cannot merge games with board sizes %s and %s", sz, sz2)
+		}
+		opt.mergeInto(res, t, sz)
+	}
+	return res, nil
+}
+
+// mergeInto merges the tree src into dst.  The nodes of src are copied
+// as needed.
+func (opt *MergeOptions) mergeInto(dst, src *Tree, sz BoardSize) {
+	type pair struct{ dst, src *Tree }
+	stack := []pair{{dst, src}}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		opt.mergeProperties(p.dst.Properties, p.src.Properties)
+
+	childLoop:
+		for _, child := range p.src.Children {
+			key := child.mergeKey(sz, opt.MatchSetup)
+			for _, cand := range p.dst.Children {
+				if cand.mergeKey(sz, opt.MatchSetup) == key {
+					stack = append(stack, pair{cand, child})
+					continue childLoop
+				}
+			}
+			p.dst.Children = append(p.dst.Children, child.clone())
+		}
+	}
+}
+
+// mergeProperties merges the properties src into dst.
+func (opt *MergeOptions) mergeProperties(dst, src Properties) {
+	for name, b := range src {
+		a, ok := dst[name]
+		if !ok {
+			dst[name] = slices.Clone(b)
+			continue
+		} else if slices.Equal(a, b) {
+			continue
+		}
+
+		switch {
+		case opt.Resolve != nil:
+			if res := opt.Resolve(name, slices.Clone(a), slices.Clone(b)); len(res) > 0 {
+				dst[name] = res
+			} else {
+				delete(dst, name)
+			}
+		case opt.Policy == KeepLast:
+			dst[name] = slices.Clone(b)
+		case opt.Policy == Combine && (name == "C" || name == "GC") && len(a) == 1 && len(b) == 1:
+			dst[name] = []string{a[0] + "\n\n" + b[0]}
+		case opt.Policy == Combine && markupProps[name]:
+			for _, val := range b {
+				if !slices.Contains(dst[name], val) {
+					dst[name] = append(dst[name], val)
+				}
+			}
+		}
+	}
+}
+
+// markupProps lists the properties whose values are combined by the
+// Combine policy.
+var markupProps = map[string]bool{
+	"AR": true, "CR": true, "DD": true, "LB": true, "LN": true, "MA": true,
+	"SL": true, "SQ": true, "TR": true, "TB": true, "TW": true, "VW": true,
+}
+
+// mergeKey returns a string which is equal for two nodes if and only if
+// the nodes can be merged.
+func (t *Tree) mergeKey(sz BoardSize, matchSetup bool) string {
+	var key []string
+	c, m, err := t.getMove(sz)
+	switch {
+	case err != nil:
+		// use the raw values for invalid moves
+		key = append(key, "B"+strings.Join(t.Properties["B"], ","),
+			"W"+strings.Join(t.Properties["W"], ","))
+	case c != Empty:
+		key = append(key, c.propertyName()+sz.EncodeMove(m))
+	}
+
+	if matchSetup {
+		for _, name := range []string{"AB", "AW", "AE"} {
+			if _, ok := t.Properties[name]; !ok {
+				continue
+			}
+			pts, err := t.Properties.GetPointList(name, sz)
+			var vals []string
+			if err != nil {
+				vals = append(vals, t.Properties[name]...)
+			} else {
+				for _, p := range pts {
+					vals = append(vals, sz.encodePoint(p))
+				}
+			}
+			sort.Strings(vals)
+			key = append(key, name+strings.Join(vals, ","))
+		}
+		if pl, ok := t.Properties["PL"]; ok {
+			key = append(key, "PL"+strings.Join(pl, ","))
+		}
+	}
+	return strings.Join(key, ";")
+}
+
+// clone returns a deep copy of the game tree with root t.
+func (t *Tree) clone() *Tree {
+	res := &Tree{}
+	type pair struct{ dst, src *Tree }
+	stack := []pair{{res, t}}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		p.dst.Properties = make(Properties, len(p.src.Properties))
+		for name, vals := range p.src.Properties {
+			p.dst.Properties[name] = slices.Clone(vals)
+		}
+		if len(p.src.Children) > 0 {
+			p.dst.Children = make([]*Tree, len(p.src.Children))
+			for i, child := range p.src.Children {
+				p.dst.Children[i] = &Tree{}
+				stack = append(stack, pair{p.dst.Children[i], child})
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,128 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// writeTree returns the SGF representation of a tree, for use in tests.
+func writeTree(t *testing.T, tree *Tree) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	err := Collection{tree}.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.ReplaceAll(strings.TrimSpace(buf.String()), "\n", "")
+}
+
+func TestMerge(t *testing.T) {
+	a := readTree(t, "(;SZ[9]KM[6.5];B[ee]C[good];W[cc];B[gg]TR[aa][bb])")
+	b := readTree(t, "(;SZ[9]KM[7];B[ee]C[ok];W[cc](;B[gc])(;B[gg]TR[bb][cc]))")
+	c := readTree(t, "(;SZ[9];B[ee];W[dd])")
+	before := writeTree(t, a) + writeTree(t, b) + writeTree(t, c)
+
+	cases := []struct {
+		opt      *MergeOptions
+		expected string
+	}{
+		{
+			nil,
+			"(;KM[6.5]SZ[9];B[ee]C[good](;W[cc](;B[gg]TR[aa][bb])(;B[gc]))(;W[dd]))",
+		},
+		{
+			&MergeOptions{Policy: KeepLast},
+			"(;KM[7]SZ[9];B[ee]C[ok](;W[cc](;B[gg]TR[bb][cc])(;B[gc]))(;W[dd]))",
+		},
+		{
+			&MergeOptions{Policy: Combine},
+			"(;KM[6.5]SZ[9];B[ee]C[good\n\nok](;W[cc](;B[gg]TR[aa][bb][cc])(;B[gc]))(;W[dd]))",
+		},
+		{
+			&MergeOptions{Resolve: func(name string, a, b []string) []string {
+				return []string{name}
+			}},
+			"(;KM[KM]SZ[9];B[ee]C[C](;W[cc](;B[gg]TR[TR])(;B[gc]))(;W[dd]))",
+		},
+		{
+			&MergeOptions{Resolve: func(name string, a, b []string) []string {
+				return nil
+			}},
+			"(;SZ[9];B[ee](;W[cc](;B[gg])(;B[gc]))(;W[dd]))",
+		},
+	}
+	for i, test := range cases {
+		res, err := MergeWithOptions(test.opt, a, b, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := writeTree(t, res)
+		expected := strings.ReplaceAll(test.expected, "\n\n", "")
+		if out != expected {
+			t.Errorf("%d: expected\n%s\ngot\n%s", i, expected, out)
+		}
+	}
+
+	res, err := MergeWithOptions(&MergeOptions{Policy: Combine}, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment, _ := res.Children[0].GetText("C"); comment != "good\n\nok" {
+		t.Errorf("wrong comment %q", comment)
+	}
+
+	after := writeTree(t, a) + writeTree(t, b) + writeTree(t, c)
+	if after != before {
+		t.Error("input trees were modified")
+	}
+}
+
+func TestMergeSetup(t *testing.T) {
+	a := readTree(t, "(;SZ[9];AB[aa:ab]C[a];B[cc])")
+	b := readTree(t, "(;SZ[9];AB[ab][aa]C[b];B[cc])")
+	c := readTree(t, "(;SZ[9];AB[dd];B[cc])")
+
+	res, err := MergeWithOptions(&MergeOptions{MatchSetup: true}, a, b, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := writeTree(t, res)
+	expected := "(;SZ[9](;AB[aa:ab]C[a];B[cc])(;AB[dd];B[cc]))"
+	if out != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	res, err = Merge(a, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsLinear() {
+		t.Error("setup nodes were not merged")
+	}
+}
+
+func TestMergeBoardSize(t *testing.T) {
+	a := readTree(t, "(;SZ[9];B[ee])")
+	b := readTree(t, "(;B[ee])")
+	_, err := Merge(a, b)
+	if err == nil {
+		t.Error("trees with different board sizes merged")
+	}
+}
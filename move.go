@@ -1,6 +1,8 @@
 package sgf
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -12,66 +14,92 @@ type BoardSize struct {
 
 func (t *Tree) GetBoardSize() (BoardSize, error) {
 	val, err := t.getSingle("SZ")
-	if _, ok := err.(*missingError); ok {
+	if errors.Is(err, ErrMissingProperty) {
 		return BoardSize{19, 19}, nil
 	} else if err != nil {
-		return BoardSize{}, err
+		return BoardSize{}, t.setNode(err)
 	}
 
 	wh := strings.Split(val, ":")
+	var w, h int
 	switch len(wh) {
 	case 1:
-		sz, err := strconv.Atoi(val)
-		if err != nil || sz < 1 || sz > 52 {
-			return BoardSize{}, newErrorf("property SZ has invalid value %q", val)
-		}
-		return BoardSize{sz, sz}, nil
+		w, err = strconv.Atoi(val)
+		h = w
 	case 2:
-		w, err := strconv.Atoi(wh[0])
-		if err != nil || w < 1 || w > 52 {
-			return BoardSize{}, newErrorf("property SZ has invalid value %q", val)
-		}
-		h, err := strconv.Atoi(wh[1])
-		if err != nil || h < 1 || h > 52 {
-			return BoardSize{}, newErrorf("property SZ has invalid value %q", val)
+		w, err = strconv.Atoi(wh[0])
+		if err == nil {
+			h, err = strconv.Atoi(wh[1])
 		}
-		return BoardSize{w, h}, nil
 	default:
-		return BoardSize{}, newErrorf("property SZ has invalid value %q", val)
+		err = ErrInvalidValue
+	}
+	if err != nil || w < 1 || w > 52 || h < 1 || h > 52 {
+		return BoardSize{}, t.setNode(newPropertyError("SZ", val, ErrInvalidValue))
 	}
+	return BoardSize{w, h}, nil
 }
 
 func (sz BoardSize) String() string {
 	return strconv.Itoa(sz.Width) + "x" + strconv.Itoa(sz.Height)
 }
 
-// Move represents a move in a game of Go.
-// The coordinates are 0-based, with (0,0) being the top left corner.
+// Move represents a move in a game of Go, or a point on the board.
+// The coordinates are 0-based, with (0,0) being the bottom left corner.
 // If the move is a pass, X and Y are -1.
 type Move struct {
 	X int8 // column, 0-based, from left to right
 	Y int8 // row, 0-based, from bottom to top
 }
 
-// in SGF, "aa" is the top left corner
-func (sz BoardSize) DecodeMove(sgfMove string) Move {
-	if sgfMove == "" || (sz.Width <= 19 && sz.Height <= 19 && sgfMove == "tt") {
-		return Move{-1, -1}
+// Pass is the Move which represents a pass.
+var Pass = Move{-1, -1}
+
+// IsPass reports whether m represents a pass.
+func (m Move) IsPass() bool {
+	return m.X < 0 || m.Y < 0
+}
+
+// ParseMove decodes the value of a B or W property.  The empty value and,
+// on boards of size up to 19x19, the value "tt" denote a pass.  If the
+// value is not valid on a board of size sz, an error wrapping
+// ErrInvalidValue is returned.
+func (sz BoardSize) ParseMove(val string) (Move, error) {
+	if val == "" || (sz.Width <= 19 && sz.Height <= 19 && val == "tt") {
+		return Pass, nil
 	}
-	if len(sgfMove) != 2 {
-		panic("invalid move " + sgfMove)
+	return sz.ParsePoint(val)
+}
+
+// ParsePoint decodes a single point, given by two coordinate letters.
+// In SGF, "aa" is the top left corner.  Passes are not allowed.  If the
+// value is not a point on a board of size sz, an error wrapping
+// ErrInvalidValue is returned.
+func (sz BoardSize) ParsePoint(val string) (Move, error) {
+	p, ok := sz.decodePoint(val)
+	if !ok {
+		return Move{}, fmt.Errorf("%w %q for %s board", ErrInvalidValue, val, sz)
 	}
-	x := int(sgfMove[0] - 'a')
-	y := (sz.Height - 1) - int(sgfMove[1]-'a')
-	if x < 0 || x >= sz.Width || y < 0 || y >= sz.Height {
-		panic("invalid move " + sgfMove)
+	return p, nil
+}
+
+// DecodeMove decodes the value of a B or W property.  It panics if the
+// value is invalid.
+//
+// Deprecated: use ParseMove instead.
+func (sz BoardSize) DecodeMove(sgfMove string) Move {
+	m, err := sz.ParseMove(sgfMove)
+	if err != nil {
+		panic(err)
 	}
-	return Move{int8(x), int8(y)}
+	return m
 }
 
+// EncodeMove encodes a move as the value of a B or W property.
+// Passes are encoded as the empty string.
 func (sz BoardSize) EncodeMove(move Move) string {
-	if move.X < 0 || move.Y < 0 {
+	if move.IsPass() {
 		return ""
 	}
-	return string([]byte{'a' + byte(move.X), 'a' + byte(sz.Height-1) - byte(move.Y)})
+	return sz.encodePoint(move)
 }
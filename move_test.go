@@ -1,6 +1,10 @@
 package sgf
 
-import "testing"
+import (
+	"errors"
+	"strings"
+	"testing"
+)
 
 func TestBoardSize(t *testing.T) {
 	type testCase struct {
@@ -59,3 +63,82 @@ func TestMove2(t *testing.T) {
 		}
 	}
 }
+
+func TestParseMove(t *testing.T) {
+	cases := []struct {
+		sz    BoardSize
+		in    string
+		out   Move
+		valid bool
+	}{
+		{BoardSize{19, 19}, "", Pass, true},
+		{BoardSize{19, 19}, "tt", Pass, true},
+		{BoardSize{9, 9}, "tt", Pass, true},
+		{BoardSize{21, 21}, "tt", Move{19, 1}, true},
+		{BoardSize{19, 19}, "aa", Move{0, 18}, true},
+		{BoardSize{19, 19}, "sa", Move{18, 18}, true},
+		{BoardSize{19, 19}, "as", Move{0, 0}, true},
+		{BoardSize{9, 9}, "ja", Move{}, false},
+		{BoardSize{19, 19}, "zz", Move{}, false},
+		{BoardSize{19, 19}, "a", Move{}, false},
+		{BoardSize{19, 19}, "abc", Move{}, false},
+		{BoardSize{19, 19}, "a?", Move{}, false},
+	}
+	for _, test := range cases {
+		m, err := test.sz.ParseMove(test.in)
+		if !test.valid {
+			if !errors.Is(err, ErrInvalidValue) {
+				t.Errorf("%s %q: expected ErrInvalidValue, got %v", test.sz, test.in, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %q: %v", test.sz, test.in, err)
+		} else if m != test.out {
+			t.Errorf("%s %q: expected %v, got %v", test.sz, test.in, test.out, m)
+		}
+	}
+
+	_, err := BoardSize{19, 19}.ParsePoint("")
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("ParsePoint accepted a pass")
+	}
+}
+
+func TestMainVariationMovesInvalid(t *testing.T) {
+	c, err := Read(strings.NewReader("(;SZ[9];B[ee];W[zz])"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c[0].MainVariationMoves()
+	var pe *PropertyError
+	if !errors.As(err, &pe) || pe.Name != "W" || pe.Value != "zz" {
+		t.Errorf("expected PropertyError for W[zz], got %v", err)
+	}
+}
+
+func FuzzMainVariationMoves(f *testing.F) {
+	for _, test := range examples {
+		f.Add(test)
+	}
+	f.Add("(;SZ[5];B[zz])")
+	f.Add("(;SZ[25];B[tt];W[yy])")
+	f.Fuzz(func(t *testing.T, in string) {
+		c, err := Read(strings.NewReader(in))
+		if err != nil {
+			return
+		}
+		for _, tree := range c {
+			moves, err := tree.MainVariationMoves()
+			if err != nil {
+				continue
+			}
+			sz, _ := tree.GetBoardSize()
+			for _, m := range moves {
+				if !m.IsPass() && !sz.contains(m) {
+					t.Errorf("move %v is not on the %s board", m, sz)
+				}
+			}
+		}
+	})
+}
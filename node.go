@@ -1,22 +1,30 @@
 package sgf
 
 import (
-	"fmt"
+	"errors"
 	"strconv"
+	"strings"
 	"unicode"
 )
 
 // Properties of a game tree node are given as a map from property names to
 // property values.
+//
+// Property values are stored in raw form, exactly as they appear between
+// the square brackets in an SGF file, including any escape characters.
+// The Get* methods decode raw values, and the Set* methods encode values
+// into raw form.  Raw values assigned directly must be well-formed: every
+// "]" and every "\" which is not part of an escape sequence must be
+// escaped by a preceding backslash.
 type Properties map[string][]string
 
 func (n Properties) getSingle(name string) (string, error) {
 	vals, ok := n[name]
 	if !ok {
-		return "", &missingError{name}
+		return "", newPropertyError(name, "", ErrMissingProperty)
 	}
 	if len(vals) != 1 {
-		return "", newErrorf("property %q has %d values, expected 1", name, len(vals))
+		return "", newPropertyError(name, "", ErrValueCount)
 	}
 	return vals[0], nil
 }
@@ -30,15 +38,21 @@ func (n Properties) GetNumber(name string) (int, error) {
 		return 0, err
 	}
 
+	val := str
 	s := 1
-	if str[0] == '-' {
-		s = -1
+	if len(str) > 0 && (str[0] == '-' || str[0] == '+') {
+		if str[0] == '-' {
+			s = -1
+		}
 		str = str[1:]
 	}
+	if str == "" {
+		return 0, newPropertyError(name, val, ErrInvalidValue)
+	}
 	abs := 0
 	for _, c := range str {
 		if c < '0' || c > '9' || abs > 1<<31/10 {
-			return 0, newErrorf("property %q has invalid value %q", name, str)
+			return 0, newPropertyError(name, val, ErrInvalidValue)
 		}
 		abs = 10*abs + int(c-'0')
 	}
@@ -52,7 +66,7 @@ func (n Properties) GetNumber(name string) (int, error) {
 // is returned.
 func (n Properties) GetNumberDefault(name string, defaultValue int) (int, error) {
 	val, err := n.GetNumber(name)
-	if _, ok := err.(*missingError); ok {
+	if errors.Is(err, ErrMissingProperty) {
 		return defaultValue, nil
 	}
 	return val, err
@@ -69,7 +83,7 @@ func (n Properties) GetReal(name string) (float64, error) {
 
 	x, err := strconv.ParseFloat(str, 64)
 	if err != nil {
-		return 0, newErrorf("property %q has invalid value %q", name, str)
+		return 0, newPropertyError(name, str, ErrInvalidValue)
 	}
 
 	return x, nil
@@ -81,7 +95,7 @@ func (n Properties) GetReal(name string) (float64, error) {
 // valid number, an error is returned.
 func (n Properties) GetRealDefault(name string, defaultValue float64) (float64, error) {
 	val, err := n.GetReal(name)
-	if _, ok := err.(*missingError); ok {
+	if errors.Is(err, ErrMissingProperty) {
 		return defaultValue, nil
 	}
 	return val, err
@@ -140,16 +154,196 @@ func (n Properties) GetSimpleText(name string) (string, error) {
 // If the property has more than one value, an error is returned.
 func (n Properties) GetSimpleTextDefault(name string, defaultValue string) (string, error) {
 	val, err := n.GetSimpleText(name)
-	if _, ok := err.(*missingError); ok {
+	if errors.Is(err, ErrMissingProperty) {
+		return defaultValue, nil
+	}
+	return val, err
+}
+
+// GetText returns the value of the property with the given name as a
+// (multi-line) text.  Line breaks are preserved and normalised to "\n";
+// soft line breaks (line breaks preceded by a backslash) are removed, and
+// all other white space is converted to spaces.  If the property is
+// missing or has more than one value, an error is returned.
+func (n Properties) GetText(name string) (string, error) {
+	s, err := n.getSingle(name)
+	if err != nil {
+		return "", err
+	}
+
+	res := make([]rune, 0, len(s))
+	escSeen := false
+	var nlIgnore rune
+	for _, r := range s {
+		skip := r == nlIgnore
+		nlIgnore = 0
+		if skip {
+			continue
+		}
+
+		if escSeen {
+			escSeen = false
+			if r == '\n' || r == '\r' {
+				nlIgnore = '\n' + '\r' - r
+				continue
+			}
+		} else if r == '\\' {
+			escSeen = true
+			continue
+		} else if r == '\n' || r == '\r' {
+			nlIgnore = '\n' + '\r' - r
+			res = append(res, '\n')
+			continue
+		}
+
+		if unicode.IsSpace(r) {
+			r = ' '
+		}
+		res = append(res, r)
+	}
+	return string(res), nil
+}
+
+// GetTextDefault returns the value of the property with the given name as
+// a (multi-line) text.  If the property is missing, the defaultValue is
+// returned.  If the property has more than one value, an error is returned.
+func (n Properties) GetTextDefault(name string, defaultValue string) (string, error) {
+	val, err := n.GetText(name)
+	if errors.Is(err, ErrMissingProperty) {
 		return defaultValue, nil
 	}
 	return val, err
 }
 
-type missingError struct {
-	name string
+// SetText sets the property with the given name to the (multi-line)
+// text s.  Line breaks are normalised to "\n".  White space other than
+// line breaks is read back as spaces by GetText.
+func (n Properties) SetText(name string, s string) {
+	s = normalizeLineBreaks(s, "\n")
+	n[name] = []string{escapeValue(s, false)}
 }
 
-func (e *missingError) Error() string {
-	return fmt.Sprintf("missing property %q", e.name)
+// SetSimpleText sets the property with the given name to the simple
+// text s.  Line breaks are converted to spaces.
+func (n Properties) SetSimpleText(name string, s string) {
+	s = normalizeLineBreaks(s, " ")
+	n[name] = []string{escapeValue(s, false)}
+}
+
+// normalizeLineBreaks replaces all line breaks ("\r\n", "\n\r", "\r" and
+// "\n") in s by nl.
+func normalizeLineBreaks(s string, nl string) string {
+	if !strings.ContainsAny(s, "\r\n") {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\n' && c != '\r' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteString(nl)
+		if i+1 < len(s) && s[i+1] == '\n'+'\r'-c {
+			i++
+		}
+	}
+	return b.String()
+}
+
+// SetValues sets the values of the property with the given name.  The
+// values are escaped as needed, so that they can be read back unchanged.
+// For text values, see also SetSimpleText and SetText.
+func (n Properties) SetValues(name string, values ...string) {
+	raw := make([]string, len(values))
+	for i, val := range values {
+		raw[i] = escapeValue(val, false)
+	}
+	n[name] = raw
+}
+
+// SetCompose sets the property with the given name to the single composed
+// value "a:b".  Colons in a and b are escaped, in addition to the
+// escaping done by SetValues.
+func (n Properties) SetCompose(name string, a, b string) {
+	n[name] = []string{escapeValue(a, true) + ":" + escapeValue(b, true)}
+}
+
+// SetNumber sets the property with the given name to the number x.
+func (n Properties) SetNumber(name string, x int) {
+	n[name] = []string{strconv.Itoa(x)}
+}
+
+// SetReal sets the property with the given name to the real number x.
+func (n Properties) SetReal(name string, x float64) {
+	n[name] = []string{strconv.FormatFloat(x, 'f', -1, 64)}
+}
+
+// escapeValue converts a decoded value into raw form.  If compose is true,
+// colons are escaped as well.
+func escapeValue(s string, compose bool) string {
+	special := "]\\"
+	if compose {
+		special = "]\\:"
+	}
+	if !strings.ContainsAny(s, special) {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s) + 4)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if strings.IndexByte(special, c) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// splitCompose splits a raw composed value at the first unescaped colon.
+// If there is no such colon, ok is false.
+func splitCompose(raw string) (a, b string, ok bool) {
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case ':':
+			return raw[:i], raw[i+1:], true
+		}
+	}
+	return raw, "", false
+}
+
+// isValidRaw reports whether a raw property value is well-formed, i.e.
+// whether all closing square brackets and backslashes are escaped.
+func isValidRaw(raw string) bool {
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+			if i >= len(raw) {
+				return false
+			}
+		case ']':
+			return false
+		}
+	}
+	return true
+}
+
+// isValidIdent reports whether name is a valid property identifier.
+func isValidIdent(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] < 'A' || name[i] > 'Z' {
+			return false
+		}
+	}
+	return true
 }
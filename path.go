@@ -0,0 +1,155 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPath is returned by ParsePath for malformed path strings.
+var ErrInvalidPath = errors.New("invalid path")
+
+// maxPathLength is the maximal number of steps in a path accepted by
+// ParsePath.  This bounds the memory used for untrusted input.
+const maxPathLength = 1 << 20
+
+// A Path identifies a node in a game tree, by listing the indices of the
+// children to follow, starting at the root.  The empty path denotes the
+// root node itself.
+type Path []int
+
+// String returns the compact string form of the path.  This is a list
+// of numbers, separated by dots: the first number gives how many steps
+// to take along the first children, starting at the root.  After this,
+// the numbers come in pairs, giving the index of the variation to
+// select, and the number of further steps along the first children.
+// A final count of zero is omitted.  For example, "57.2.3" denotes the
+// node reached by following the main variation for 57 steps, then
+// taking the child with index 2, and then following the main variation
+// for 3 more steps.  The root node is denoted by "0".
+func (p Path) String() string {
+	var parts []string
+	run := 0
+	for _, idx := range p {
+		if idx == 0 {
+			run++
+			continue
+		}
+		parts = append(parts, strconv.Itoa(run), strconv.Itoa(idx))
+		run = 0
+	}
+	if run > 0 || len(parts) == 0 {
+		parts = append(parts, strconv.Itoa(run))
+	}
+	return strings.Join(parts, ".")
+}
+
+// ParsePath decodes the string form of a path, as produced by
+// Path.String.  If s is malformed, or if the path has more than 2^20
+// steps, an error wrapping ErrInvalidPath is returned.
+func ParsePath(s string) (Path, error) {
+	var res Path
+	for i, part := range strings.Split(s, ".") {
+		k, err := strconv.Atoi(part)
+		if err != nil || k < 0 || part[0] == '+' {
+			return nil, fmt.Errorf("%w %q", ErrInvalidPath, s)
+		}
+		steps := k // number of path elements added by this part
+		if i%2 == 1 {
+			steps = 1
+		}
+		if steps > maxPathLength-len(res) {
+			return nil, fmt.Errorf("%w %q: too many steps", ErrInvalidPath, s)
+		}
+
+		if i%2 == 1 {
+			res = append(res, k)
+			continue
+		}
+		for ; k > 0; k-- {
+			res = append(res, 0)
+		}
+	}
+	return res, nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, using the
+// string form of the path.
+func (p Path) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (p *Path) UnmarshalText(text []byte) error {
+	res, err := ParsePath(string(text))
+	if err != nil {
+		return err
+	}
+	*p = res
+	return nil
+}
+
+// child returns a new path, leading to the child with index i of the node
+// p leads to.
+func (p Path) child(i int) Path {
+	res := make(Path, len(p)+1)
+	copy(res, p)
+	res[len(p)] = i
+	return res
+}
+
+// Resolve returns the node reached from the root t by following p.  If
+// the path does not lead to a node of the tree, for example because
+// the tree has been edited, an error wrapping ErrNoSuchNode is returned.
+func (p Path) Resolve(t *Tree) (*Tree, error) {
+	n := t
+	for i, idx := range p {
+		if idx < 0 || idx >= len(n.Children) {
+			return nil, fmt.Errorf("%w: path %v, step %d", ErrNoSuchNode, p, i+1)
+		}
+		n = n.Children[idx]
+	}
+	return n, nil
+}
+
+// PathTo returns the path from the root t to the node n.  If n is not
+// part of the tree, an error wrapping ErrNoSuchNode is returned.
+func (t *Tree) PathTo(n *Tree) (Path, error) {
+	type task struct {
+		node  *Tree
+		depth int
+		idx   int
+	}
+	var path Path
+	stack := []task{{node: t}}
+	for len(stack) > 0 {
+		tk := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		path = append(path[:tk.depth], tk.idx)
+		if tk.node == n {
+			return append(Path{}, path[1:]...), nil
+		}
+		for i := len(tk.node.Children) - 1; i >= 0; i-- {
+			stack = append(stack, task{tk.node.Children[i], tk.depth + 1, i})
+		}
+	}
+	return nil, fmt.Errorf("%w: node not found", ErrNoSuchNode)
+}
@@ -0,0 +1,100 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPathString(t *testing.T) {
+	cases := []struct {
+		path Path
+		s    string
+	}{
+		{Path{}, "0"},
+		{Path{0, 0, 0}, "3"},
+		{Path{2}, "0.2"},
+		{Path{0, 0, 1}, "2.1"},
+		{Path{0, 0, 2, 0, 0, 0}, "2.2.3"},
+		{Path{1, 3, 0, 1}, "0.1.0.3.1.1"},
+	}
+	for _, test := range cases {
+		s := test.path.String()
+		if s != test.s {
+			t.Errorf("%v: expected %q, got %q", []int(test.path), test.s, s)
+		}
+		p, err := ParsePath(s)
+		if err != nil {
+			t.Errorf("%q: %v", s, err)
+			continue
+		}
+		if len(p) != len(test.path) || (len(p) > 0 && !cmp.Equal(p, test.path)) {
+			t.Errorf("%q: round trip gave %v", s, []int(p))
+		}
+	}
+
+	p, err := ParsePath("57.2.3")
+	if err != nil || len(p) != 61 || p[57] != 2 {
+		t.Errorf("wrong path %v, %v", []int(p), err)
+	}
+
+	for _, s := range []string{"", "a", "1..2", "1.-2", "+1", "1.2.",
+		"999999999999", "99999999999999999999999", "1048576.1", "524288.1.524288"} {
+		_, err := ParsePath(s)
+		if !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("%q: expected ErrInvalidPath, got %v", s, err)
+		}
+	}
+}
+
+func TestPathResolve(t *testing.T) {
+	tree := readTree(t, "(;GM[1];B[aa](;W[bb];B[cc])(;W[dd];B[ee]))")
+	n, err := Path{0, 1, 0}.Resolve(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Properties["B"][0] != "ee" {
+		t.Errorf("wrong node %v", n.Properties)
+	}
+
+	p, err := tree.PathTo(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(Path{0, 1, 0}, p); d != "" {
+		t.Errorf("wrong path (-want +got):\n%s", d)
+	}
+	p, err = tree.PathTo(tree)
+	if err != nil || len(p) != 0 {
+		t.Errorf("wrong path to root: %v, %v", p, err)
+	}
+
+	// after deleting the variation, the path no longer exists
+	c := NewCursor(tree)
+	c.Child(0)
+	c.Child(1)
+	c.DeleteSubtree()
+	if _, err := (Path{0, 1, 0}).Resolve(tree); !errors.Is(err, ErrNoSuchNode) {
+		t.Errorf("expected ErrNoSuchNode, got %v", err)
+	}
+	if _, err := tree.PathTo(n); !errors.Is(err, ErrNoSuchNode) {
+		t.Errorf("expected ErrNoSuchNode, got %v", err)
+	}
+}
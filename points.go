@@ -0,0 +1,263 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+// GetPointList returns the value of the property with the given name as a
+// list of points on a board of size sz.  Compressed lists, where "aa:cc"
+// denotes all points in the rectangle with corners aa and cc, are expanded.
+// A single empty value denotes the empty list.  Duplicate points are
+// included only once.  If the property is missing or a value is not a
+// valid point or rectangle, an error is returned.
+func (n Properties) GetPointList(name string, sz BoardSize) ([]Move, error) {
+	values, ok := n[name]
+	if !ok {
+		return nil, newPropertyError(name, "", ErrMissingProperty)
+	}
+	if len(values) == 1 && values[0] == "" {
+		return nil, nil
+	}
+
+	var res []Move
+	seen := make(map[Move]bool)
+	for _, val := range values {
+		a, b, isRect := splitCompose(val)
+		p1, ok1 := sz.decodePoint(a)
+		p2, ok2 := p1, ok1
+		if isRect {
+			p2, ok2 = sz.decodePoint(b)
+		}
+		if !ok1 || !ok2 {
+			return nil, newPropertyError(name, val, ErrInvalidValue)
+		}
+
+		// iterate over the rectangle in SGF order, i.e. row by row from
+		// the top, and from left to right within each row
+		x0, x1 := minMax(p1.X, p2.X)
+		y0, y1 := minMax(p1.Y, p2.Y)
+		for y := y1; y >= y0; y-- {
+			for x := x0; x <= x1; x++ {
+				p := Move{x, y}
+				if !seen[p] {
+					seen[p] = true
+					res = append(res, p)
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// SetPointList sets the property with the given name to the list of
+// points pts on a board of size sz.  Each point is stored as a separate
+// value; an empty list is stored as a single empty value.  An error is
+// returned if a point is not on the board.
+func (n Properties) SetPointList(name string, sz BoardSize, pts []Move) error {
+	if len(pts) == 0 {
+		n[name] = []string{""}
+		return nil
+	}
+
+	values := make([]string, len(pts))
+	for i, p := range pts {
+		if !sz.contains(p) {
+			return newPropertyError(name, "", ErrInvalidValue)
+		}
+		values[i] = sz.encodePoint(p)
+	}
+	n[name] = values
+	return nil
+}
+
+// SetPointListCompressed is like SetPointList, but uses the compressed
+// FF[4] format where rectangular blocks of points are stored as a single
+// value "aa:cc".  Duplicate points are ignored, and the order of the
+// points is not preserved.
+func (n Properties) SetPointListCompressed(name string, sz BoardSize, pts []Move) error {
+	if len(pts) == 0 {
+		n[name] = []string{""}
+		return nil
+	}
+
+	in := make([]bool, sz.Width*sz.Height)
+	for _, p := range pts {
+		if !sz.contains(p) {
+			return newPropertyError(name, "", ErrInvalidValue)
+		}
+		in[int(p.Y)*sz.Width+int(p.X)] = true
+	}
+
+	// Greedily cover the points by rectangles, starting from the top left:
+	// each rectangle is extended to the right as far as possible, and then
+	// downwards as long as all points in the next row are available.
+	var values []string
+	for y := sz.Height - 1; y >= 0; y-- {
+		for x := 0; x < sz.Width; x++ {
+			if !in[y*sz.Width+x] {
+				continue
+			}
+			x1 := x
+			for x1+1 < sz.Width && in[y*sz.Width+x1+1] {
+				x1++
+			}
+			y1 := y
+		extendDown:
+			for y1 > 0 {
+				for i := x; i <= x1; i++ {
+					if !in[(y1-1)*sz.Width+i] {
+						break extendDown
+					}
+				}
+				y1--
+			}
+			for j := y1; j <= y; j++ {
+				for i := x; i <= x1; i++ {
+					in[j*sz.Width+i] = false
+				}
+			}
+
+			val := sz.encodePoint(Move{int8(x), int8(y)})
+			if x1 > x || y1 < y {
+				val += ":" + sz.encodePoint(Move{int8(x1), int8(y1)})
+			}
+			values = append(values, val)
+		}
+	}
+	n[name] = values
+	return nil
+}
+
+// decodePoint decodes a point given by two SGF coordinate letters.
+func (sz BoardSize) decodePoint(s string) (Move, bool) {
+	if len(s) != 2 {
+		return Move{}, false
+	}
+	x := decodeCoord(s[0])
+	y := decodeCoord(s[1])
+	if x < 0 || x >= sz.Width || y < 0 || y >= sz.Height {
+		return Move{}, false
+	}
+	return Move{int8(x), int8(sz.Height - 1 - y)}, true
+}
+
+// encodePoint encodes a point on the board as two SGF coordinate letters.
+func (sz BoardSize) encodePoint(p Move) string {
+	return string([]byte{encodeCoord(int(p.X)), encodeCoord(sz.Height - 1 - int(p.Y))})
+}
+
+// contains reports whether p is a point on the board.
+func (sz BoardSize) contains(p Move) bool {
+	return p.X >= 0 && int(p.X) < sz.Width && p.Y >= 0 && int(p.Y) < sz.Height
+}
+
+// decodeCoord converts an SGF coordinate letter to a 0-based index:
+// "a" to "z" represent 0 to 25, and "A" to "Z" represent 26 to 51.
+// For invalid letters, -1 is returned.
+func decodeCoord(c byte) int {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return int(c - 'a')
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 26
+	default:
+		return -1
+	}
+}
+
+func encodeCoord(i int) byte {
+	if i < 26 {
+		return 'a' + byte(i)
+	}
+	return 'A' + byte(i-26)
+}
+
+func minMax(a, b int8) (int8, int8) {
+	if a > b {
+		return b, a
+	}
+	return a, b
+}
+
+// GetPointList is like Properties.GetPointList, but takes the board size
+// from the SZ property of t.  Since nodes do not know their parents, this
+// is meant for the root node; see Cursor.GetPointList for other nodes.
+func (t *Tree) GetPointList(name string) ([]Move, error) {
+	sz, err := t.GetBoardSize()
+	if err != nil {
+		return nil, err
+	}
+	pts, err := t.Properties.GetPointList(name, sz)
+	return pts, t.setNode(err)
+}
+
+// SetPointList is like Properties.SetPointList, but takes the board size
+// from the SZ property of t.
+func (t *Tree) SetPointList(name string, pts []Move) error {
+	sz, err := t.GetBoardSize()
+	if err != nil {
+		return err
+	}
+	return t.Properties.SetPointList(name, sz, pts)
+}
+
+// SetPointListCompressed is like Properties.SetPointListCompressed, but
+// takes the board size from the SZ property of t.
+func (t *Tree) SetPointListCompressed(name string, pts []Move) error {
+	sz, err := t.GetBoardSize()
+	if err != nil {
+		return err
+	}
+	return t.Properties.SetPointListCompressed(name, sz, pts)
+}
+
+// BoardSize returns the board size given by the SZ property of the root
+// node of the game tree.
+func (c *Cursor) BoardSize() (BoardSize, error) {
+	return c.nodes[0].GetBoardSize()
+}
+
+// GetPointList is like Properties.GetPointList for the current node, but
+// takes the board size from the root node of the game tree.
+func (c *Cursor) GetPointList(name string) ([]Move, error) {
+	sz, err := c.BoardSize()
+	if err != nil {
+		return nil, err
+	}
+	n := c.Node()
+	pts, err := n.Properties.GetPointList(name, sz)
+	return pts, n.setNode(err)
+}
+
+// SetPointList is like Properties.SetPointList for the current node, but
+// takes the board size from the root node of the game tree.
+func (c *Cursor) SetPointList(name string, pts []Move) error {
+	sz, err := c.BoardSize()
+	if err != nil {
+		return err
+	}
+	return c.Node().Properties.SetPointList(name, sz, pts)
+}
+
+// SetPointListCompressed is like Properties.SetPointListCompressed for
+// the current node, but takes the board size from the root node of the
+// game tree.
+func (c *Cursor) SetPointListCompressed(name string, pts []Move) error {
+	sz, err := c.BoardSize()
+	if err != nil {
+		return err
+	}
+	return c.Node().Properties.SetPointListCompressed(name, sz, pts)
+}
@@ -0,0 +1,204 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestGetPointList(t *testing.T) {
+	sz := BoardSize{9, 9}
+	cases := []struct {
+		values []string
+		out    []Move
+	}{
+		{[]string{""}, nil},
+		{[]string{"aa"}, []Move{{0, 8}}},
+		{[]string{"aa", "ii"}, []Move{{0, 8}, {8, 0}}},
+		{[]string{"aa:bb"}, []Move{{0, 8}, {1, 8}, {0, 7}, {1, 7}}},
+		{[]string{"bb:aa"}, []Move{{0, 8}, {1, 8}, {0, 7}, {1, 7}}},
+		{[]string{"ca:cc", "cb"}, []Move{{2, 8}, {2, 7}, {2, 6}}},
+	}
+	for _, test := range cases {
+		n := Properties{"AB": test.values}
+		got, err := n.GetPointList("AB", sz)
+		if err != nil {
+			t.Errorf("%q: %v", test.values, err)
+			continue
+		}
+		if d := cmp.Diff(test.out, got); d != "" {
+			t.Errorf("%q: unexpected points (-want +got):\n%s", test.values, d)
+		}
+	}
+}
+
+func TestGetPointListErrors(t *testing.T) {
+	sz := BoardSize{19, 19}
+	cases := [][]string{
+		{"tt"},
+		{"a"},
+		{"aa", ""},
+		{"aa:"},
+		{"aa:zz"},
+		{"aa:bb:cc"},
+	}
+	for _, values := range cases {
+		n := Properties{"AB": values}
+		_, err := n.GetPointList("AB", sz)
+		if !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("%q: expected ErrInvalidValue, got %v", values, err)
+		}
+	}
+
+	_, err := Properties{}.GetPointList("AB", sz)
+	if !errors.Is(err, ErrMissingProperty) {
+		t.Errorf("expected ErrMissingProperty, got %v", err)
+	}
+}
+
+func TestPointListLargeBoard(t *testing.T) {
+	sz := BoardSize{52, 52}
+	n := Properties{}
+	pts := []Move{{0, 0}, {25, 26}, {26, 25}, {51, 51}}
+	err := n.SetPointList("TR", sz, pts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff([]string{"aZ", "zz", "AA", "Za"}, n["TR"]); d != "" {
+		t.Errorf("unexpected values (-want +got):\n%s", d)
+	}
+	got, err := n.GetPointList("TR", sz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(pts, got); d != "" {
+		t.Errorf("unexpected points (-want +got):\n%s", d)
+	}
+}
+
+func TestSetPointListCompressed(t *testing.T) {
+	sz := BoardSize{5, 5}
+	n := Properties{}
+	var pts []Move
+	for x := int8(0); x < 3; x++ {
+		for y := int8(2); y < 5; y++ {
+			pts = append(pts, Move{x, y})
+		}
+	}
+	pts = append(pts, Move{4, 0})
+	err := n.SetPointListCompressed("AB", sz, pts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff([]string{"aa:cc", "ee"}, n["AB"]); d != "" {
+		t.Errorf("unexpected values (-want +got):\n%s", d)
+	}
+
+	err = n.SetPointListCompressed("AB", sz, []Move{{5, 0}})
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected ErrInvalidValue, got %v", err)
+	}
+}
+
+func TestPointListRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	sz := BoardSize{13, 11}
+	for i := 0; i < 100; i++ {
+		seen := make(map[Move]bool)
+		var pts []Move
+		for j := rng.Intn(80); j > 0; j-- {
+			p := Move{int8(rng.Intn(sz.Width)), int8(rng.Intn(sz.Height))}
+			if !seen[p] {
+				seen[p] = true
+				pts = append(pts, p)
+			}
+		}
+
+		n := Properties{}
+		err := n.SetPointListCompressed("AW", sz, pts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := n.GetPointList("AW", sz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sortPoints(pts)
+		sortPoints(got)
+		if d := cmp.Diff(pts, got, cmpopts.EquateEmpty()); d != "" {
+			t.Errorf("%q: unexpected points (-want +got):\n%s", n["AW"], d)
+		}
+	}
+}
+
+func TestTreePointList(t *testing.T) {
+	tree := readTree(t, "(;SZ[5]AB[aa:bb])")
+	pts, err := tree.GetPointList("AB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff([]Move{{0, 4}, {1, 4}, {0, 3}, {1, 3}}, pts); d != "" {
+		t.Errorf("unexpected points (-want +got):\n%s", d)
+	}
+
+	if err := tree.SetPointListCompressed("AW", []Move{{4, 0}, {4, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff([]string{"ed:ee"}, tree.Properties["AW"]); d != "" {
+		t.Errorf("unexpected AW values (-want +got):\n%s", d)
+	}
+	if err := tree.SetPointList("TR", []Move{{5, 5}}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected ErrInvalidValue, got %v", err)
+	}
+}
+
+func TestCursorPointList(t *testing.T) {
+	tree := readTree(t, "(;SZ[5];B[aa]\n;AE[ee:ff])")
+	c := NewCursor(tree)
+	c.Child(0)
+	if err := c.SetPointList("TR", []Move{{0, 0}, {4, 4}}); err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff([]string{"ae", "ea"}, c.Node().Properties["TR"]); d != "" {
+		t.Errorf("unexpected TR values (-want +got):\n%s", d)
+	}
+	if err := c.SetPointList("SQ", []Move{{5, 0}}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected ErrInvalidValue, got %v", err)
+	}
+
+	c.Child(0)
+	_, err := c.GetPointList("AE")
+	var pe *PropertyError
+	if !errors.As(err, &pe) || pe.Node != c.Node() {
+		t.Errorf("expected error for the current node, got %v", err)
+	}
+}
+
+func sortPoints(pts []Move) {
+	sort.Slice(pts, func(i, j int) bool {
+		if pts[i].Y != pts[j].Y {
+			return pts[i].Y < pts[j].Y
+		}
+		return pts[i].X < pts[j].X
+	})
+}
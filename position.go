@@ -0,0 +1,213 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoSuchNode is returned when a path does not lead to a node of a
+// game tree.
+var ErrNoSuchNode = errors.New("no such node")
+
+// A Position describes the state of a game at a node of a game tree.
+type Position struct {
+	// Board gives the stones on the board, and the number of stones
+	// captured by each player.
+	Board *Board
+
+	// ToPlay is the player to move next.  This is the opponent of the
+	// player who made the last move, or the value of the PL property
+	// if the node contains setup properties.
+	ToPlay Color
+}
+
+// StartPosition returns the position before the root node t is applied:
+// an empty board of the size given by the SZ property, with black to play.
+func (t *Tree) StartPosition() (*Position, error) {
+	sz, err := t.GetBoardSize()
+	if err != nil {
+		return nil, err
+	}
+	return &Position{
+		Board:  NewBoard(sz),
+		ToPlay: Black,
+	}, nil
+}
+
+// PositionAt returns the position after the node reached from the root t
+// by following path.  The moves and setup properties of all nodes along
+// the path are replayed, using default rules.
+func (t *Tree) PositionAt(path Path) (*Position, error) {
+	pos, err := t.StartPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	n := t
+	err = pos.Apply(n)
+	for i, idx := range path {
+		if err != nil {
+			return nil, err
+		}
+		if idx < 0 || idx >= len(n.Children) {
+			return nil, fmt.Errorf("%w: path %v, step %d", ErrNoSuchNode, path, i+1)
+		}
+		n = n.Children[idx]
+		err = pos.Apply(n)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pos, nil
+}
+
+// Next returns the position after the node n, which normally is a child of
+// the node p belongs to.  This allows to step through a game tree without
+// replaying all moves from the root.  The position p is not modified.
+func (p *Position) Next(n *Tree) (*Position, error) {
+	res := p.Clone()
+	err := res.Apply(n)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Hash returns a Zobrist hash of the board position.  This is the same as
+// p.Board.Hash().
+func (p *Position) Hash() uint64 {
+	return p.Board.Hash()
+}
+
+// SituationalHash returns a Zobrist hash of the board position together
+// with the player to move.
+func (p *Position) SituationalHash() uint64 {
+	return p.Board.Hash() ^ p.Board.zobrist.toPlay[p.ToPlay]
+}
+
+// Clone returns a copy of the position.
+func (p *Position) Clone() *Position {
+	return &Position{
+		Board:  p.Board.Clone(),
+		ToPlay: p.ToPlay,
+	}
+}
+
+// Apply modifies the position by applying the properties of the node n.
+// Setup properties (AE, AB, AW and PL) are applied first, followed by a
+// move (B or W), if any.  If a property is invalid, or if the move is
+// illegal, an error is returned and the position may be partially
+// modified.
+func (p *Position) Apply(n *Tree) error {
+	sz := p.Board.Size
+	setup := []struct {
+		name  string
+		color Color
+	}{
+		{"AE", Empty},
+		{"AB", Black},
+		{"AW", White},
+	}
+	for _, s := range setup {
+		if _, ok := n.Properties[s.name]; !ok {
+			continue
+		}
+		pts, err := n.Properties.GetPointList(s.name, sz)
+		if err != nil {
+			return n.setNode(err)
+		}
+		for _, pt := range pts {
+			p.Board.Set(pt, s.color)
+		}
+	}
+	if _, ok := n.Properties["PL"]; ok {
+		c, err := n.getColor("PL")
+		if err != nil {
+			return n.setNode(err)
+		}
+		p.ToPlay = c
+	}
+
+	c, m, err := n.getMove(sz)
+	if err != nil {
+		return n.setNode(err)
+	}
+	if c == Empty {
+		return nil
+	}
+	_, err = p.Board.Play(c, m)
+	if err != nil {
+		return n.setNode(newPropertyError(c.propertyName(), sz.EncodeMove(m), err))
+	}
+	p.ToPlay = c.Opponent()
+	return nil
+}
+
+// getMove returns the move stored in the B or W property of a node.
+// If the node contains no move, Empty is returned for the color.
+func (n Properties) getMove(sz BoardSize) (Color, Move, error) {
+	_, bOk := n["B"]
+	_, wOk := n["W"]
+	var c Color
+	switch {
+	case bOk && wOk:
+		return Empty, Move{}, newPropertyError("W", "", ErrConflictingMoves)
+	case bOk:
+		c = Black
+	case wOk:
+		c = White
+	default:
+		return Empty, Move{}, nil
+	}
+
+	name := c.propertyName()
+	val, err := n.getSingle(name)
+	if err != nil {
+		return Empty, Move{}, err
+	}
+	m, err := sz.ParseMove(val)
+	if err != nil {
+		return Empty, Move{}, newPropertyError(name, val, ErrInvalidValue)
+	}
+	return c, m, nil
+}
+
+// getColor returns the value of a property of type Color, like PL.
+func (n Properties) getColor(name string) (Color, error) {
+	val, err := n.getSingle(name)
+	if err != nil {
+		return Empty, err
+	}
+	switch val {
+	case "B":
+		return Black, nil
+	case "W":
+		return White, nil
+	}
+	return Empty, newPropertyError(name, val, ErrInvalidValue)
+}
+
+// propertyName returns the SGF property identifier used for moves of
+// the player of color c.
+func (c Color) propertyName() string {
+	if c == White {
+		return "W"
+	}
+	return "B"
+}
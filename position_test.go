@@ -0,0 +1,123 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func readTree(t *testing.T, in string) *Tree {
+	t.Helper()
+	c, err := Read(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c[0]
+}
+
+func TestPositionAt(t *testing.T) {
+	tree := readTree(t, `(;SZ[5]AB[aa:ab]AW[ba]PL[W]
+		(;W[bb];B[cc];W[ac])
+		(;W[ee];B[bb]AE[ee]))`)
+
+	cases := []struct {
+		path    []int
+		diagram string
+		toPlay  Color
+		capW    int
+	}{
+		{nil, `
+			XO...
+			X....
+			.....
+			.....
+			.....`, White, 0},
+		{[]int{0, 0, 0}, `
+			.O...
+			.O...
+			O.X..
+			.....
+			.....`, Black, 2},
+		{[]int{1, 0}, `
+			XO...
+			XX...
+			.....
+			.....
+			.....`, White, 0},
+	}
+	for _, test := range cases {
+		pos, err := tree.PositionAt(test.path)
+		if err != nil {
+			t.Errorf("%v: %v", test.path, err)
+			continue
+		}
+		if got, want := pos.Board.String(), normalizeDiagram(test.diagram); got != want {
+			t.Errorf("%v: expected\n%s\ngot\n%s", test.path, want, got)
+		}
+		if pos.ToPlay != test.toPlay {
+			t.Errorf("%v: expected %s to play, got %s", test.path, test.toPlay, pos.ToPlay)
+		}
+		if pos.Board.Captures(White) != test.capW {
+			t.Errorf("%v: expected %d white captures, got %d",
+				test.path, test.capW, pos.Board.Captures(White))
+		}
+	}
+
+	_, err := tree.PositionAt([]int{2})
+	if !errors.Is(err, ErrNoSuchNode) {
+		t.Errorf("expected ErrNoSuchNode, got %v", err)
+	}
+}
+
+func TestPositionNext(t *testing.T) {
+	tree := readTree(t, "(;SZ[9];B[ee];W[ec](;B[cc])(;B[gg]))")
+	pos, err := tree.PositionAt([]int{0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := tree.Children[0].Children[0]
+	for i, child := range parent.Children {
+		next, err := pos.Next(child)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := tree.PositionAt([]int{0, 0, i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next.Board.String() != expected.Board.String() || next.ToPlay != expected.ToPlay {
+			t.Errorf("variation %d: incremental replay differs from full replay", i)
+		}
+	}
+	if pos.Board.At(Move{2, 6}) != Empty {
+		t.Error("Next modified the original position")
+	}
+}
+
+func TestPositionIllegal(t *testing.T) {
+	tree := readTree(t, "(;SZ[9];B[ee];W[ee])")
+	_, err := tree.PositionAt([]int{0, 0})
+	if !errors.Is(err, ErrOccupied) {
+		t.Fatalf("expected ErrOccupied, got %v", err)
+	}
+	var pe *PropertyError
+	if !errors.As(err, &pe) || pe.Name != "W" || pe.Node != tree.Children[0].Children[0] {
+		t.Errorf("expected PropertyError for the last node, got %v", err)
+	}
+}
@@ -17,6 +17,7 @@
 package sgf
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -35,185 +36,539 @@ func ReadFile(fileName string) (Collection, error) {
 
 // Read reads a collection of games from r.
 func Read(r io.Reader) (Collection, error) {
-	body, err := io.ReadAll(r)
-	if err != nil {
-		return nil, err
-	}
+	c, _, err := ReadWithOptions(r, nil)
+	return c, err
+}
 
-	c, err := parse(string(body))
-	if err != nil {
-		return nil, err
-	}
+// ReadOptions controls how SGF input is parsed.
+type ReadOptions struct {
+	// Lenient enables the repair of common defects found in real-world
+	// SGF files: lower case letters in property identifiers (FF[3] style,
+	// e.g. "AddBlack"), stray text between nodes, missing semicolons,
+	// missing closing brackets at the end of input, and empty game trees.
+	// Each repair is reported as a Warning.
+	Lenient bool
 
-	return c, nil
+	// Charset, if non-empty, overrides the character set given by the
+	// CA property of each game tree.
+	//
+	// Property values are converted from the character set of the game
+	// tree to UTF-8.  If all values could be converted, the CA property of
+	// the root node is changed to "UTF-8".  Values which cannot be
+	// converted are reported as warnings, and leave the CA property
+	// unchanged.  If no character set is given, neither here nor in a CA
+	// property, the input is assumed to be UTF-8.  Unknown character sets
+	// are reported as warnings and leave the input unchanged.
+	// See RegisterCharset for how to add support for more character sets.
+	Charset string
 }
 
-type parser struct {
-	tokens  <-chan *token
-	backlog []*token
-}
+// ReadWithOptions reads a collection of games from r.  If opt is nil,
+// default options are used.  Problems which were repaired in lenient mode,
+// as well as problems with character set conversion, are returned as a
+// list of warnings.
+//
+// If the input contains syntax errors, the whole input is still read
+// and the returned error is an ErrorList which describes all problems
+// found.
+func ReadWithOptions(r io.Reader, opt *ReadOptions) (Collection, []Warning, error) {
+	d := NewDecoder(r, opt)
 
-func parse(s string) (Collection, error) {
-	tokens := make(chan *token)
-	scanner := &scanner{
-		input:  s,
-		tokens: tokens,
+	var c Collection
+	var errs ErrorList
+	for {
+		tree, err := d.Decode()
+		if err == io.EOF {
+			break
+		} else if list, ok := err.(ErrorList); ok {
+			errs = append(errs, list...)
+			continue
+		} else if err != nil {
+			return nil, d.Warnings(), err
+		}
+		c = append(c, tree)
 	}
-	go scanner.run()
+	if errs != nil {
+		return nil, d.Warnings(), errs
+	}
+	return c, d.Warnings(), nil
+}
 
-	p := &parser{
-		tokens: tokens,
+// A Decoder reads the game trees of a collection from an input stream,
+// one at a time.  Input is read incrementally, so that the memory
+// used is bounded by the size of the largest game tree in the input.
+type Decoder struct {
+	p   *parser
+	err error
+}
+
+// NewDecoder returns a new decoder that reads from r.  If opt is nil,
+// default options are used.
+//
+// The decoder introduces its own buffering and may read data from r
+// beyond the game trees requested.
+func NewDecoder(r io.Reader, opt *ReadOptions) *Decoder {
+	if opt == nil {
+		opt = &ReadOptions{}
 	}
-	c, err := p.parseCollection()
-	if err != nil {
-		// drain the lexer
-		for range tokens {
-		}
-		return nil, err
+	p := &parser{
+		scanner: newScanner(r),
+		lenient: opt.Lenient,
+		charset: opt.Charset,
 	}
-
-	return c, nil
+	return &Decoder{p: p}
 }
 
-func (p *parser) parseCollection() (Collection, error) {
-	var c Collection
+// Decode reads the next game tree from the input.  The tree is returned
+// as soon as its closing bracket has been read.  At the end of the input,
+// Decode returns io.EOF.
+//
+// If a game tree contains syntax errors, Decode returns an ErrorList
+// describing all problems in the tree.  The decoder then resynchronises
+// and the next call to Decode returns the following game tree.  Read
+// errors are permanent: once a read error has occurred, all further
+// calls return the same error.
+func (d *Decoder) Decode() (*Tree, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
 
-gameLoop:
+	p := d.p
+	p.positions = make(map[*Tree]srcPos)
 	for {
-		t := p.peek()
+		p.skipUntil(tokenParenOpen)
+		if p.scanner.err != nil {
+			d.err = p.scanner.err
+			return nil, d.err
+		} else if p.errors != nil {
+			return nil, p.takeErrors()
+		} else if p.peek().typ == tokenEOF {
+			d.err = io.EOF
+			return nil, d.err
+		}
 
-		switch t.typ {
-		case tokenEOF:
-			break gameLoop
-		default:
-			g, err := p.parseGameTree()
-			if err != nil {
-				return nil, err
-			}
-			c = append(c, g)
+		tree := p.parseGameTree()
+		if p.scanner.err != nil {
+			d.err = p.scanner.err
+			return nil, d.err
+		} else if p.errors != nil {
+			return nil, p.takeErrors()
+		}
+		if tree != nil {
+			p.convertCharset(tree)
+			return tree, nil
 		}
 	}
-	return c, nil
 }
 
-func (p *parser) parseGameTree() (*Tree, error) {
-	err := p.require(tokenParenOpen, "GameTree")
-	if err != nil {
-		return nil, err
+// Locate adds the source position of the node to err, if err wraps a
+// *PropertyError whose Node was returned by the most recent call to
+// Decode.  Positions of earlier game trees are not kept, so that the
+// memory used does not grow with the size of the input.
+func (d *Decoder) Locate(err error) error {
+	var e *PropertyError
+	if !errors.As(err, &e) || e.Line > 0 {
+		return err
+	}
+	if pos, ok := d.p.positions[e.Node]; ok {
+		e.Line = pos.line
+		e.Column = pos.col
+		e.Offset = pos.offset
 	}
+	return err
+}
 
-	root := &Tree{}
-	tree := root
+// Warnings returns the warnings generated since the previous call to
+// Warnings.
+func (d *Decoder) Warnings() []Warning {
+	res := d.p.warnings
+	d.p.warnings = nil
+	return res
+}
 
-	for {
-		n, err := p.parseNode()
-		if err != nil {
-			return nil, err
-		}
-		tree.Properties = n
-		if p.peek().typ != tokenSemicolon {
-			break
+// A parser builds game trees from the tokens returned by a scanner.
+//
+// Problems in the input are handled in the same way in strict and in
+// lenient mode: the parser resynchronises at the next node or game tree
+// boundary and continues.  The only difference is that in lenient mode,
+// problems are reported as warnings and the repaired tree is kept, whereas
+// in strict mode they are reported as errors and the tree is discarded.
+type parser struct {
+	scanner  *scanner
+	lenient  bool
+	charset  string
+	warnings []Warning
+	errors   ErrorList
+
+	// positions records the source positions of the nodes of the current
+	// game tree.
+	positions map[*Tree]srcPos
+
+	// lookahead holds a token which has been pushed back by backup().
+	lookahead    token
+	hasLookahead bool
+}
+
+// convertCharset converts the property values of a game tree to UTF-8.
+func (p *parser) convertCharset(tree *Tree) {
+	name := p.charset
+	if name == "" {
+		name, _ = tree.getSingle("CA")
+	}
+	if name == "" {
+		return
+	}
+
+	cs := LookupCharset(name)
+	if cs == nil {
+		p.warnNode(tree, fmt.Sprintf("unknown character set %q", name))
+		return
+	}
+	transcode(tree, cs, func(n *Tree, err error) {
+		p.warnNode(n, err.Error())
+	})
+}
+
+// parseGameTree parses a game tree, starting at the opening bracket.
+// Empty game trees are skipped and nil is returned.
+//
+// Nested game trees are handled using an explicit stack instead of
+// recursion, so that deeply nested input cannot exhaust the call stack.
+func (p *parser) parseGameTree() *Tree {
+	open := p.next()
+	if open.typ != tokenParenOpen {
+		p.problem(open, "expected GameTree, got %q", open)
+		return nil
+	}
+
+	// Set up the scanner for the character set of the game tree, before
+	// any property values are scanned.
+	// Values before the CA property may contain two-byte characters, whose
+	// second byte can look like a closing bracket, so both interpretations
+	// are tried.
+	name := p.charset
+	if name == "" {
+		name = p.scanner.peekCharset(nil)
+		if _, ok := LookupCharset(name).(MultiByteCharset); !ok {
+			alt := p.scanner.peekCharset(isHighByte)
+			if _, ok := LookupCharset(alt).(MultiByteCharset); ok {
+				name = alt
+			}
 		}
-		child := &Tree{}
-		tree.Children = []*Tree{child}
-		tree = child
+	}
+	p.scanner.lead = nil
+	if cs, ok := LookupCharset(name).(MultiByteCharset); ok {
+		p.scanner.lead = cs.IsLeadByte
 	}
 
-childLoop:
+	var root *Tree
+	var stack []*Tree // the last node of the sequence of each open game tree
 	for {
-		t := p.peek()
-		switch t.typ {
-		case tokenParenClose:
-			break childLoop
-		default:
-			child, err := p.parseGameTree()
-			if err != nil {
-				return nil, err
+		// parse the sequence of nodes following an opening bracket
+		p.skipUntil(tokenSemicolon, tokenPropIdent, tokenParenClose)
+		if t := p.peek(); t.typ == tokenParenClose || t.typ == tokenEOF {
+			p.problem(open, "empty game tree")
+			if t.typ == tokenParenClose {
+				p.next()
+			}
+			if len(stack) == 0 {
+				return nil
+			}
+		} else {
+			first := &Tree{}
+			tree := first
+			for {
+				t := p.peek()
+				p.positions[tree] = srcPos{line: t.line + 1, col: t.col + 1, offset: t.pos}
+				tree.Properties = p.parseNode()
+				p.skipUntil(tokenSemicolon, tokenParenOpen, tokenParenClose)
+				if p.peek().typ != tokenSemicolon {
+					break
+				}
+				child := &Tree{}
+				tree.Children = []*Tree{child}
+				tree = child
+			}
+
+			if len(stack) == 0 {
+				root = first
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, first)
 			}
-			tree.Children = append(tree.Children, child)
+			stack = append(stack, tree)
 		}
-	}
 
-	err = p.require(tokenParenClose, "closing round bracket")
-	if err != nil {
-		return nil, err
+		// close game trees, until the next variation starts
+	closeLoop:
+		for {
+			p.skipUntil(tokenParenOpen, tokenParenClose)
+			t := p.peek()
+			switch t.typ {
+			case tokenParenClose:
+				p.next()
+				stack = stack[:len(stack)-1]
+				if len(stack) == 0 {
+					return root
+				}
+			case tokenParenOpen:
+				open = p.next()
+				break closeLoop
+			default:
+				for range stack {
+					p.problem(t, "missing closing round bracket")
+				}
+				return root
+			}
+		}
 	}
-
-	return root, nil
 }
 
-func (p *parser) parseNode() (Properties, error) {
-	err := p.require(tokenSemicolon, "Node")
-	if err != nil {
-		return nil, err
+// parseNode parses a node, starting at the semicolon.
+func (p *parser) parseNode() Properties {
+	t := p.next()
+	if t.typ != tokenSemicolon {
+		p.problem(t, "missing semicolon before node")
+		p.backup(t)
 	}
 
 	n := make(Properties)
 	for {
+		p.skipUntil(tokenPropIdent, tokenSemicolon, tokenParenOpen, tokenParenClose)
 		t := p.next()
 		if t.typ != tokenPropIdent {
 			p.backup(t)
 			break
 		}
-		key := t.val
+		identTok := t
+		key := p.checkIdent(t)
 
 		var values []string
 		for {
 			t = p.next()
-			if t.typ != tokenPropValue {
+			if t.typ == tokenUnterminated {
+				p.problem(t, "missing closing square bracket at end of input")
+				values = append(values, t.val)
+				break
+			} else if t.typ != tokenPropValue {
 				p.backup(t)
 				break
 			}
 			values = append(values, t.val)
 		}
+		if key == "" {
+			continue
+		}
 		if len(values) == 0 {
-			return nil, makeError(p.peek(), "property %q has no values", key)
+			p.problem(t, "property %q has no values", key)
+			continue
 		}
 
+		if _, ok := n[key]; ok {
+			if p.lenient {
+				p.problem(identTok, "duplicate property %q, values appended", key)
+			} else {
+				p.problem(identTok, "duplicate property %q", key)
+			}
+			values = append(n[key], values...)
+		}
 		n[key] = values
 	}
-	return n, nil
+	return n
 }
 
-func (p *parser) next() *token {
-	if len(p.backlog) > 0 {
-		n := len(p.backlog) - 1
-		t := p.backlog[n]
-		p.backlog = p.backlog[:n]
-		return t
+// checkIdent returns the property identifier for an identifier token.
+// Lower case letters are removed from the identifier; if no upper case
+// letters are left, the empty string is returned.
+func (p *parser) checkIdent(t token) string {
+	upper := 0
+	for i := 0; i < len(t.val); i++ {
+		if c := t.val[i]; c >= 'A' && c <= 'Z' {
+			upper++
+		}
 	}
-	return <-p.tokens
+	if upper == len(t.val) {
+		return t.val
+	} else if upper == 0 {
+		p.problem(t, "unexpected %q", t)
+		return ""
+	}
+
+	key := make([]byte, 0, upper)
+	for i := 0; i < len(t.val); i++ {
+		if c := t.val[i]; c >= 'A' && c <= 'Z' {
+			key = append(key, c)
+		}
+	}
+	if p.lenient {
+		p.problem(t, "property identifier %q changed to %q", t.val, key)
+	} else {
+		p.problem(t, "invalid property identifier %q", t.val)
+	}
+	return string(key)
 }
 
-func (p *parser) backup(t *token) {
-	p.backlog = append(p.backlog, t)
+// skipUntil discards tokens until a token of one of the given types,
+// a read error, or the end of input is found.  Each run of discarded
+// tokens is reported as a single problem.
+func (p *parser) skipUntil(types ...tokenType) {
+	var first token
+	skipped := 0
+	for {
+		t := p.peek()
+		if t.typ == tokenEOF || t.typ == tokenError {
+			break
+		}
+		found := false
+		for _, tp := range types {
+			if t.typ == tp {
+				found = true
+				break
+			}
+		}
+		if found {
+			break
+		}
+		p.next()
+		if skipped == 0 {
+			first = t
+		}
+		skipped++
+	}
+	if skipped > 0 {
+		p.problem(first, "unexpected %q", first)
+	}
+}
+
+func (p *parser) next() token {
+	if p.hasLookahead {
+		p.hasLookahead = false
+		return p.lookahead
+	}
+	return p.scanner.next()
+}
+
+// backup pushes back a token, so that it is returned by the next call to
+// next().  At most one token can be pushed back at a time.
+func (p *parser) backup(t token) {
+	p.lookahead = t
+	p.hasLookahead = true
 }
 
-func (p *parser) peek() *token {
+func (p *parser) peek() token {
 	t := p.next()
 	p.backup(t)
 	return t
 }
 
-func (p *parser) require(tp tokenType, desc string) error {
-	t := p.next()
-	if t.typ != tp {
-		return makeError(t, "expected %s, got %q", desc, t)
+// problem reports a defect in the input.  In lenient mode, the
+// problem is recorded as a warning, otherwise as an error.
+func (p *parser) problem(t token, format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	if p.lenient {
+		p.warnings = append(p.warnings, Warning{
+			Line:   t.line + 1,
+			Column: t.col + 1,
+			Offset: t.pos,
+			Msg:    msg,
+		})
+	} else {
+		p.errors = append(p.errors, &ParseError{
+			Line:   t.line + 1,
+			Column: t.col + 1,
+			Offset: t.pos,
+			Msg:    msg,
+		})
 	}
-	return nil
 }
 
-type parseError struct {
-	next *token
-	msg  string
+// warnNode records a warning for the given node.  This is used for
+// problems which are not syntax errors, and thus are reported as warnings
+// also in strict mode.
+func (p *parser) warnNode(n *Tree, msg string) {
+	pos := p.positions[n]
+	p.warnings = append(p.warnings, Warning{
+		Line:   pos.line,
+		Column: pos.col,
+		Offset: pos.offset,
+		Msg:    msg,
+	})
 }
 
-func (err *parseError) Error() string {
-	return fmt.Sprintf("line %d, column %d: %s",
-		err.next.line+1, err.next.col+1, err.msg)
+// srcPos gives a position in the SGF input.  Line and column are 1 based;
+// the zero value indicates an unknown position.
+type srcPos struct {
+	line, col, offset int
 }
 
-func makeError(next *token, format string, a ...interface{}) error {
-	msg := fmt.Sprintf(format, a...)
-	return &parseError{next: next, msg: msg}
+func (p *parser) takeErrors() ErrorList {
+	res := p.errors
+	p.errors = nil
+	return res
+}
+
+// A Warning describes a defect in the input which has been repaired
+// while reading in lenient mode, or a problem with character set
+// conversion.
+type Warning struct {
+	Line   int // 1 based
+	Column int // 1 based, counted in bytes
+	Offset int // byte offset, 0 based
+	Msg    string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("line %d, column %d: %s", w.Line, w.Column, w.Msg)
+}
+
+// A ParseError describes a syntax error in SGF input.
+type ParseError struct {
+	Line   int // 1 based
+	Column int // 1 based, counted in bytes
+	Offset int // byte offset, 0 based
+	Msg    string
+}
+
+func (err *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", err.Line, err.Column, err.Msg)
+}
+
+// An ErrorList is a list of parse errors, in the order in which they
+// occur in the input.  A non-empty ErrorList can be used as an error
+// value; errors.As can be used to find the individual ParseErrors.
+type ErrorList []*ParseError
+
+func (list ErrorList) Error() string {
+	switch len(list) {
+	case 0:
+		return "no errors"
+	case 1:
+		return list[0].Error()
+	case 2:
+		return fmt.Sprintf("%s (and 1 more error)", list[0])
+	}
+	return fmt.Sprintf("%s (and %d more errors)", list[0], len(list)-1)
+}
+
+// Is reports whether one of the errors in the list matches target.
+// This allows errors.Is to look inside the list.
+func (list ErrorList) Is(target error) bool {
+	for _, err := range list {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error in the list which matches target, and if
+// one is found, sets target to that error value and returns true.  This
+// allows errors.As to find the individual ParseErrors.
+func (list ErrorList) As(target interface{}) bool {
+	for _, err := range list {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
 }
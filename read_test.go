@@ -1,8 +1,16 @@
 package sgf
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestRead(t *testing.T) {
@@ -25,3 +33,262 @@ func TestRead(t *testing.T) {
 		t.Errorf("expected SZ[9], got %v", tree.Properties["SZ"])
 	}
 }
+
+func TestDecoder(t *testing.T) {
+	in := "(;GN[a])\n(;GN[b];B[aa])  (;GN[c])\n"
+	d := NewDecoder(strings.NewReader(in), nil)
+
+	for _, name := range []string{"a", "b", "c"} {
+		tree, err := d.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := tree.GetSimpleText("GN")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != name {
+			t.Errorf("expected game %q, got %q", name, got)
+		}
+	}
+	_, err := d.Decode()
+	if err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestDecoderIncremental(t *testing.T) {
+	r, w := io.Pipe()
+	go func() {
+		// The second game tree is only written once the first one has
+		// been decoded.
+		_, _ = io.WriteString(w, "(;GN[a];B[aa];W[bb])")
+	}()
+
+	d := NewDecoder(r, nil)
+	done := make(chan error)
+	go func() {
+		tree, err := d.Decode()
+		if err == nil && len(tree.MainVariation()) != 3 {
+			err = fmt.Errorf("expected 3 nodes, got %d", len(tree.MainVariation()))
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Decode did not return after the first game tree")
+	}
+
+	go func() {
+		_, _ = io.WriteString(w, "(;GN[b])")
+		w.Close()
+	}()
+	tree, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Children) != 0 {
+		t.Errorf("expected 0 children, got %d", len(tree.Children))
+	}
+	_, err = d.Decode()
+	if err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestDecoderError(t *testing.T) {
+	readErr := errors.New("read error")
+	r := io.MultiReader(strings.NewReader("(;GN[a])(;GN"), iotest.ErrReader(readErr))
+	d := NewDecoder(r, nil)
+	_, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = d.Decode()
+	if err != readErr {
+		t.Errorf("expected read error, got %v", err)
+	}
+	_, err = d.Decode()
+	if err != readErr {
+		t.Errorf("expected read error again, got %v", err)
+	}
+}
+
+func BenchmarkRead(b *testing.B) {
+	body := benchmarkCollection(200)
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := Read(strings.NewReader(body))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	body := benchmarkCollection(200)
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d := NewDecoder(strings.NewReader(body), nil)
+		for {
+			_, err := d.Decode()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// benchmarkCollection returns a collection of n games, each with 250 moves
+// and occasional comments.
+func benchmarkCollection(n int) string {
+	rng := rand.New(rand.NewSource(1))
+	buf := &strings.Builder{}
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(buf, "(;FF[4]GM[1]SZ[19]PB[Black %d]PW[White %d]KM[6.5]\n", i, i)
+		for j := 0; j < 250; j++ {
+			col := "BW"[j%2]
+			x := 'a' + rng.Intn(19)
+			y := 'a' + rng.Intn(19)
+			fmt.Fprintf(buf, ";%c[%c%c]", col, x, y)
+			if j%20 == 0 {
+				buf.WriteString("C[a comment, with \\] and\nmore text]")
+			}
+			if j%10 == 9 {
+				buf.WriteByte('\n')
+			}
+		}
+		buf.WriteString(")\n")
+	}
+	return buf.String()
+}
+
+func TestLenient(t *testing.T) {
+	cases := []struct {
+		in       string
+		out      string
+		warnings int
+	}{
+		{"(;FF[3]AddBlack[aa][bb])", "(;AB[aa][bb]\nFF[3])\n", 1},
+		{"(;AB[aa]AddBlack[bb])", "(;AB[aa][bb])\n", 2},
+		{"(;AB[aa]AB[bb][cc])", "(;AB[aa][bb][cc])\n", 1},
+		{"(;B[aa] some text ;W[bb])", "(;B[aa];W[bb])\n", 2},
+		{"(;B[aa]\n%%% 123\n;W[bb])", "(;B[aa];W[bb])\n", 1},
+		{"(;B[aa];W[bb]", "(;B[aa];W[bb])\n", 1},
+		{"(;B[aa](;W[bb](;B[cc]", "(;B[aa];W[bb];B[cc])\n", 3},
+		{"(;B[aa]()(;W[bb]))", "(;B[aa];W[bb])\n", 1},
+		{"()(;B[aa])", "(;B[aa])\n", 1},
+		{"garbage (;B[aa])", "(;B[aa])\n", 1},
+		{"(B[aa])", "(;B[aa])\n", 1},
+		{"(;C[cut off", "(;C[cut off])\n", 2},
+		{"(;B[aa])", "(;B[aa])\n", 0},
+	}
+	for _, test := range cases {
+		c, warnings, err := ReadWithOptions(strings.NewReader(test.in),
+			&ReadOptions{Lenient: true})
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		buf := &strings.Builder{}
+		err = c.Write(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != test.out {
+			t.Errorf("%q: expected %q, got %q", test.in, test.out, buf.String())
+		}
+		if len(warnings) != test.warnings {
+			t.Errorf("%q: expected %d warnings, got %v", test.in, test.warnings, warnings)
+		}
+
+		_, err = Read(strings.NewReader(test.in))
+		if err == nil && test.warnings > 0 {
+			t.Errorf("%q: strict mode accepted invalid input", test.in)
+		}
+	}
+}
+
+func TestWarningPosition(t *testing.T) {
+	in := "(;B[aa]\n  ;W[bb]Hello;B[cc])"
+	_, warnings, err := ReadWithOptions(strings.NewReader(in),
+		&ReadOptions{Lenient: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) == 0 {
+		t.Fatal("no warnings")
+	}
+	w := warnings[0]
+	if w.Line != 2 || w.Column != 9 {
+		t.Errorf("expected warning at 2:9, got %s", w)
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	in := "(;B[aa]x;W[bb])\n(;GN[ok])\n(;B[cc]Foo[1];W[dd]"
+	_, _, err := ReadWithOptions(strings.NewReader(in), nil)
+
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected ErrorList, got %v", err)
+	}
+	expected := []ParseError{
+		{Line: 1, Column: 8, Offset: 7},
+		{Line: 3, Column: 8, Offset: 33},
+		{Line: 3, Column: 20, Offset: 45},
+	}
+	if len(list) != len(expected) {
+		t.Fatalf("expected %d errors, got %d: %v", len(expected), len(list), list)
+	}
+	for i, e := range expected {
+		got := list[i]
+		if got.Line != e.Line || got.Column != e.Column || got.Offset != e.Offset {
+			t.Errorf("%d: expected error at %d:%d (offset %d), got %d:%d (offset %d)",
+				i, e.Line, e.Column, e.Offset, got.Line, got.Column, got.Offset)
+		}
+	}
+
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe != list[0] {
+		t.Error("errors.As did not find the first ParseError")
+	}
+	if !errors.Is(err, list[2]) || errors.Is(err, io.EOF) {
+		t.Error("errors.Is did not look inside the ErrorList")
+	}
+}
+
+func TestDecoderResync(t *testing.T) {
+	in := "(;GN[a])(;GN[b]%)(;GN[c])"
+	d := NewDecoder(strings.NewReader(in), nil)
+
+	var names []string
+	var errs int
+	for {
+		tree, err := d.Decode()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			errs++
+			continue
+		}
+		name, _ := tree.GetSimpleText("GN")
+		names = append(names, name)
+	}
+	if errs != 1 {
+		t.Errorf("expected 1 error, got %d", errs)
+	}
+	if d := cmp.Diff([]string{"a", "c"}, names); d != "" {
+		t.Errorf("unexpected games (-want +got):\n%s", d)
+	}
+}
@@ -0,0 +1,203 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ResultKind describes the outcome of a game, as given in the RE property.
+type ResultKind int
+
+// These are the possible values for ResultKind.
+const (
+	// ResultUnknown means that the result is not known ("?").
+	ResultUnknown ResultKind = iota
+
+	// ResultWin means that one of the players won the game.
+	ResultWin
+
+	// ResultDraw means that the game ended in a draw ("0", "Draw").
+	ResultDraw
+
+	// ResultVoid means that the game was suspended or ended without
+	// result ("Void").
+	ResultVoid
+)
+
+// ResultReason describes how a game was won.
+type ResultReason int
+
+// These are the possible values for ResultReason.
+const (
+	// ReasonUnspecified is used if the reason is not given ("B+").
+	ReasonUnspecified ResultReason = iota
+
+	// ReasonScore means that the game was decided by counting.  The
+	// margin is given in Result.Margin.
+	ReasonScore
+
+	// ReasonResign means that the loser resigned ("B+R").
+	ReasonResign
+
+	// ReasonTime means that the loser ran out of time ("B+T").
+	ReasonTime
+
+	// ReasonForfeit means that the loser forfeited the game ("B+F").
+	ReasonForfeit
+)
+
+// A Result gives the outcome of a game, as stored in the RE property.
+type Result struct {
+	Kind ResultKind
+
+	// Winner is the winner of the game, if Kind is ResultWin.  For all
+	// other kinds, Winner is Empty.
+	Winner Color
+
+	// Reason gives how the game was won, if Kind is ResultWin.
+	Reason ResultReason
+
+	// Margin is the number of points by which the winner was ahead, if
+	// Reason is ReasonScore.
+	Margin float64
+}
+
+// ParseResult decodes the value of an RE property.  In addition to the
+// forms given in the SGF specification, some common variants are
+// accepted: letters may be in either case, surrounding white space is
+// ignored, reasons may be spelled out ("B+Resign", "W+Time",
+// "B+Forfeit"), and "Jigo" denotes a draw.  Score margins must be plain
+// decimal numbers like "3" or "6.5".  If the value cannot be
+// decoded, an error wrapping ErrInvalidValue is returned.
+func ParseResult(val string) (Result, error) {
+	s := strings.ToUpper(strings.TrimSpace(val))
+	switch s {
+	case "?":
+		return Result{Kind: ResultUnknown}, nil
+	case "0", "DRAW", "JIGO":
+		return Result{Kind: ResultDraw}, nil
+	case "VOID":
+		return Result{Kind: ResultVoid}, nil
+	}
+
+	res := Result{Kind: ResultWin}
+	switch {
+	case strings.HasPrefix(s, "B+"):
+		res.Winner = Black
+	case strings.HasPrefix(s, "W+"):
+		res.Winner = White
+	default:
+		return Result{}, fmt.Errorf("%w %q for RE", ErrInvalidValue, val)
+	}
+
+	switch reason := strings.TrimSpace(s[2:]); reason {
+	case "":
+		res.Reason = ReasonUnspecified
+	case "R", "RES", "RESIGN":
+		res.Reason = ReasonResign
+	case "T", "TIME":
+		res.Reason = ReasonTime
+	case "F", "FORFEIT":
+		res.Reason = ReasonForfeit
+	default:
+		if !isDecimal(reason) {
+			return Result{}, fmt.Errorf("%w %q for RE", ErrInvalidValue, val)
+		}
+		margin, err := strconv.ParseFloat(reason, 64)
+		if err != nil {
+			return Result{}, fmt.Errorf("%w %q for RE", ErrInvalidValue, val)
+		}
+		res.Reason = ReasonScore
+		res.Margin = margin
+	}
+	return res, nil
+}
+
+// isDecimal reports whether s is a decimal number of the form "123" or
+// "123.45".  Signs, exponents and special values like "NaN" are not
+// allowed.
+func isDecimal(s string) bool {
+	intPart, frac, hasFrac := strings.Cut(s, ".")
+	return isDigits(intPart) && (!hasFrac || isDigits(frac))
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the canonical form of the result, as used in the RE
+// property.
+func (r Result) String() string {
+	switch r.Kind {
+	case ResultDraw:
+		return "0"
+	case ResultVoid:
+		return "Void"
+	case ResultWin:
+		if r.Winner == Black || r.Winner == White {
+			break
+		}
+		fallthrough
+	default:
+		return "?"
+	}
+
+	res := r.Winner.propertyName() + "+"
+	switch r.Reason {
+	case ReasonScore:
+		res += strconv.FormatFloat(r.Margin, 'f', -1, 64)
+	case ReasonResign:
+		res += "R"
+	case ReasonTime:
+		res += "T"
+	case ReasonForfeit:
+		res += "F"
+	}
+	return res
+}
+
+// GetResult returns the result of the game, as given by the RE property
+// of t.  If the property is missing, has more than one value, or cannot
+// be decoded by ParseResult, an error is returned.
+func (t *Tree) GetResult() (Result, error) {
+	val, err := t.GetSimpleText("RE")
+	if err != nil {
+		return Result{}, t.setNode(err)
+	}
+	res, err := ParseResult(val)
+	if err != nil {
+		return Result{}, t.setNode(newPropertyError("RE", val, ErrInvalidValue))
+	}
+	return res, nil
+}
+
+// SetResult sets the RE property of t to the canonical form of r.
+func (t *Tree) SetResult(r Result) {
+	t.SetSimpleText("RE", r.String())
+}
@@ -0,0 +1,98 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseResult(t *testing.T) {
+	cases := []struct {
+		in    string
+		res   Result
+		canon string
+	}{
+		{"B+3.5", Result{ResultWin, Black, ReasonScore, 3.5}, "B+3.5"},
+		{"W+0.5 ", Result{ResultWin, White, ReasonScore, 0.5}, "W+0.5"},
+		{"W+12", Result{ResultWin, White, ReasonScore, 12}, "W+12"},
+		{"W+R", Result{ResultWin, White, ReasonResign, 0}, "W+R"},
+		{"B+Resign", Result{ResultWin, Black, ReasonResign, 0}, "B+R"},
+		{"b+res", Result{ResultWin, Black, ReasonResign, 0}, "B+R"},
+		{"B+T", Result{ResultWin, Black, ReasonTime, 0}, "B+T"},
+		{"W+Time", Result{ResultWin, White, ReasonTime, 0}, "W+T"},
+		{"B+F", Result{ResultWin, Black, ReasonForfeit, 0}, "B+F"},
+		{"B+", Result{ResultWin, Black, ReasonUnspecified, 0}, "B+"},
+		{"0", Result{Kind: ResultDraw}, "0"},
+		{"Draw", Result{Kind: ResultDraw}, "0"},
+		{"Jigo", Result{Kind: ResultDraw}, "0"},
+		{"Void", Result{Kind: ResultVoid}, "Void"},
+		{"?", Result{Kind: ResultUnknown}, "?"},
+	}
+	for _, test := range cases {
+		res, err := ParseResult(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if res != test.res {
+			t.Errorf("%q: expected %+v, got %+v", test.in, test.res, res)
+		}
+		if s := res.String(); s != test.canon {
+			t.Errorf("%q: expected canonical form %q, got %q", test.in, test.canon, s)
+		}
+
+		// the canonical form must read back unchanged
+		res2, err := ParseResult(res.String())
+		if err != nil || res2 != res {
+			t.Errorf("%q: round trip failed: %+v, %v", test.in, res2, err)
+		}
+	}
+}
+
+func TestParseResultInvalid(t *testing.T) {
+	for _, in := range []string{"", "X+1", "B+-1", "B++1", "W+lots", "B 3.5",
+		"B+NaN", "W+Inf", "B+Infinity", "W+1e3", "B+0x10", "W+1_000", "B+.5", "W+3."} {
+		_, err := ParseResult(in)
+		if !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("%q: expected ErrInvalidValue, got %v", in, err)
+		}
+	}
+}
+
+func TestTreeResult(t *testing.T) {
+	tree := readTree(t, "(;RE[W+Resign])")
+	res, err := tree.GetResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Winner != White || res.Reason != ReasonResign {
+		t.Errorf("wrong result %+v", res)
+	}
+
+	tree.SetResult(Result{Kind: ResultWin, Winner: Black, Reason: ReasonScore, Margin: 6.5})
+	if val := tree.Properties["RE"]; len(val) != 1 || val[0] != "B+6.5" {
+		t.Errorf("wrong RE property %q", val)
+	}
+
+	tree = readTree(t, "(;RE[nobody knows])")
+	_, err = tree.GetResult()
+	var perr *PropertyError
+	if !errors.As(err, &perr) || perr.Name != "RE" || perr.Node != tree {
+		t.Errorf("expected PropertyError for RE, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"strings"
+)
+
+// Rules describes the variant of the rules of Go used by a Board.
+type Rules struct {
+	// AllowSuicide indicates whether a move which leaves the player's own
+	// group without liberties is legal.  If suicide is allowed, the group
+	// is removed from the board and counted as captured by the opponent.
+	AllowSuicide bool
+
+	// Superko selects which repetitions of earlier positions are
+	// forbidden, in addition to simple ko.  Since this requires the
+	// history of the game, superko is not checked by Board.Play.
+	Superko SuperkoRule
+
+	// Scoring selects how the score of a finished game is counted.
+	Scoring ScoringMethod
+
+	// Compensation selects how many extra points white receives in
+	// handicap games.
+	Compensation HandicapCompensation
+}
+
+// SuperkoRule describes a variant of the superko rule.
+type SuperkoRule int
+
+// These are the supported variants of the superko rule.
+const (
+	// NoSuperko means that only simple ko is forbidden.
+	NoSuperko SuperkoRule = iota
+
+	// PositionalSuperko forbids moves which recreate an earlier board
+	// position.
+	PositionalSuperko
+
+	// SituationalSuperko forbids moves which recreate an earlier board
+	// position with the same player to move.
+	SituationalSuperko
+)
+
+// ScoringMethod describes how the score of a finished game is counted.
+type ScoringMethod int
+
+// These are the supported scoring methods.
+const (
+	// TerritoryScoring counts the empty points surrounded by a player,
+	// plus the number of prisoners taken.
+	TerritoryScoring ScoringMethod = iota
+
+	// AreaScoring counts the empty points surrounded by a player, plus
+	// the number of the player's stones on the board.
+	AreaScoring
+)
+
+// HandicapCompensation describes how many points white receives in
+// handicap games, in addition to komi.
+type HandicapCompensation int
+
+// These are the supported variants of handicap compensation.  In all
+// cases, no compensation is given for games with fewer than two
+// handicap stones.
+const (
+	// NoCompensation gives no extra points to white.
+	NoCompensation HandicapCompensation = iota
+
+	// CompensateHandicap gives white one point for every handicap stone.
+	CompensateHandicap
+
+	// CompensateHandicapMinusOne gives white one point for every
+	// handicap stone after the first.
+	CompensateHandicapMinusOne
+)
+
+// points returns the number of points white receives for the given
+// number of handicap stones.
+func (c HandicapCompensation) points(handicap int) int {
+	if handicap < 2 {
+		return 0
+	}
+	switch c {
+	case CompensateHandicap:
+		return handicap
+	case CompensateHandicapMinusOne:
+		return handicap - 1
+	}
+	return 0
+}
+
+// RulesByName returns the rules for the rule set with the given name, as
+// used in the RU property.  Recognised names are "Japanese", "Korean",
+// "Chinese", "AGA", "GOE" (or "Ing"), "NZ" (or "New Zealand"), and
+// "Tromp-Taylor", ignoring case.  If the name is not recognised, ok is
+// false.
+func RulesByName(name string) (rules Rules, ok bool) {
+	switch normalizeRulesName(name) {
+	case "japanese", "jp", "korean":
+		return Rules{}, true
+	case "chinese", "cn":
+		return Rules{
+			Superko:      PositionalSuperko,
+			Scoring:      AreaScoring,
+			Compensation: CompensateHandicap,
+		}, true
+	case "aga":
+		return Rules{
+			Superko:      SituationalSuperko,
+			Scoring:      AreaScoring,
+			Compensation: CompensateHandicapMinusOne,
+		}, true
+	case "goe", "ing":
+		return Rules{
+			AllowSuicide: true,
+			Superko:      PositionalSuperko,
+			Scoring:      AreaScoring,
+			Compensation: CompensateHandicap,
+		}, true
+	case "nz", "newzealand":
+		return Rules{
+			AllowSuicide: true,
+			Superko:      SituationalSuperko,
+			Scoring:      AreaScoring,
+		}, true
+	case "tromptaylor", "tt":
+		return Rules{
+			AllowSuicide: true,
+			Superko:      PositionalSuperko,
+			Scoring:      AreaScoring,
+		}, true
+	}
+	return Rules{}, false
+}
+
+func normalizeRulesName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '_', ' ', '.':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// GetRules returns the rules given by the RU property of the root node t.
+// If the property is missing, or if the rule set is not recognised by
+// RulesByName, an error is returned.
+func (t *Tree) GetRules() (Rules, error) {
+	name, err := t.GetSimpleText("RU")
+	if err != nil {
+		return Rules{}, t.setNode(err)
+	}
+	rules, ok := RulesByName(name)
+	if !ok {
+		return Rules{}, t.setNode(newPropertyError("RU", name, ErrInvalidValue))
+	}
+	return rules, nil
+}
@@ -0,0 +1,70 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRulesByName(t *testing.T) {
+	cases := []struct {
+		name  string
+		rules Rules
+	}{
+		{"Japanese", Rules{}},
+		{"chinese", Rules{Superko: PositionalSuperko, Scoring: AreaScoring, Compensation: CompensateHandicap}},
+		{"AGA", Rules{Superko: SituationalSuperko, Scoring: AreaScoring, Compensation: CompensateHandicapMinusOne}},
+		{"New Zealand", Rules{AllowSuicide: true, Superko: SituationalSuperko, Scoring: AreaScoring}},
+		{"Tromp-Taylor", Rules{AllowSuicide: true, Superko: PositionalSuperko, Scoring: AreaScoring}},
+	}
+	for _, test := range cases {
+		rules, ok := RulesByName(test.name)
+		if !ok || rules != test.rules {
+			t.Errorf("%s: expected %v, got %v, %t", test.name, test.rules, rules, ok)
+		}
+	}
+
+	if _, ok := RulesByName("Calvinball"); ok {
+		t.Error("unknown rules accepted")
+	}
+}
+
+func TestSuperkoFromRU(t *testing.T) {
+	moves := ";B[cb];W[];B[];W[bb])"
+	for ru, illegal := range map[string]bool{"Japanese": false, "Chinese": true} {
+		tree := readTree(t, "(;SZ[5:4]RU["+ru+"]AB[ba][ab][bc]AW[ca][bb][db][cc]"+moves)
+		rules, err := tree.GetRules()
+		if err != nil {
+			t.Fatal(err)
+		}
+		errs, err := tree.CheckMoves(rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := len(errs) == 1 && errors.Is(errs[0], ErrSuperko)
+		if found != illegal {
+			t.Errorf("%s: unexpected result %v", ru, errs)
+		}
+	}
+
+	tree := readTree(t, "(;RU[Calvinball])")
+	_, err := tree.GetRules()
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected ErrInvalidValue, got %v", err)
+	}
+}
@@ -17,186 +17,356 @@
 package sgf
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
-	"unicode"
-	"unicode/utf8"
+	"io"
+	"strings"
 )
 
-// stateFn represents the state of the scanner
-// as a function that returns the next state.
-type stateFn func(*scanner) stateFn
-
-// A scanner breaks a string into tokens.
+// A scanner breaks the input into tokens.
+//
+// Tokens are produced on demand by calls to next().  The scanner does not
+// judge whether a token is allowed at the current position; this is left
+// to the parser.  The input is read
+// incrementally.  All syntactically relevant characters in SGF are ASCII,
+// so no UTF-8 decoding is needed and the bytes of property values are
+// passed through unchanged.
 type scanner struct {
-	input  string
-	start  int // start position of current token
-	pos    int // current position in input
-	width  int // width of last rune read from input
-	tokens chan<- *token
-
-	eolSeen   bool
-	lineStart int
+	input *bufio.Reader
+	buf   []byte // scratch space for the current token
+	err   error  // the first read error, other than io.EOF
+
+	// lead, if non-nil, reports which bytes start a two-byte character
+	// in the character set of the current game tree.  The byte following
+	// such a lead byte is never treated as SGF syntax.
+	lead func(byte) bool
+
+	pos       int // current position in input
+	lineStart int // position of the first byte of the current line
 	lineNo    int // 0 based
+
+	// interned holds copies of short property identifiers and values seen
+	// so far, so that repeated occurrences (like the "B" and "W"
+	// identifiers, or move coordinates) share memory.  Only strings of at
+	// most two bytes are interned, so that the map stays bounded in size
+	// even for very long inputs.
+	interned map[string]string
 }
 
-func (s *scanner) run() {
-	for state := scanStart; state != nil; {
-		state = state(s)
+func newScanner(r io.Reader) *scanner {
+	return &scanner{
+		input:    bufio.NewReader(r),
+		interned: make(map[string]string),
 	}
-	close(s.tokens)
 }
 
-func (s *scanner) next() rune {
-	if s.eolSeen {
-		s.eolSeen = false
-		s.lineStart = s.pos
-		s.lineNo++
-	}
+// next returns the next token from the input.  Once the end of input
+// or a read error has been reached, all further calls return a tokenEOF
+// or tokenError token, respectively.
+func (s *scanner) next() token {
+	s.skipWhiteSpace()
 
-	if s.pos >= len(s.input) {
-		s.width = 0
-		return eof
+	t := token{
+		pos:  s.pos,
+		line: s.lineNo,
+		col:  s.pos - s.lineStart,
 	}
-	r, w := utf8.DecodeRuneInString(s.input[s.pos:])
-	s.pos += w
-	s.width = w
-
-	if r == '\n' {
-		s.eolSeen = true
+	c, ok := s.readByte()
+	switch {
+	case !ok && s.err != nil:
+		t.typ = tokenError
+		t.val = s.err.Error()
+	case !ok:
+		t.typ = tokenEOF
+	case c == '(':
+		t.typ = tokenParenOpen
+	case c == ')':
+		t.typ = tokenParenClose
+	case c == ';':
+		t.typ = tokenSemicolon
+	case c == '[':
+		s.scanPropValue(&t)
+	case isLetter(c):
+		s.scanPropIdent(&t, c)
+	default:
+		s.scanInvalid(&t, c)
 	}
-
-	return r
+	return t
 }
 
-func (s *scanner) backup() {
-	s.pos -= s.width
-	s.width = 0
+func (s *scanner) readByte() (byte, bool) {
+	c, err := s.input.ReadByte()
+	if err != nil {
+		if err != io.EOF && s.err == nil {
+			s.err = err
+		}
+		return 0, false
+	}
+	s.pos++
+	if c == '\n' {
+		s.lineNo++
+		s.lineStart = s.pos
+	}
+	return c, true
 }
 
-func (s *scanner) emit(t tokenType) {
-	s.tokens <- &token{
-		typ:  t,
-		val:  s.input[s.start:s.pos],
-		line: s.lineNo,
-		col:  s.start - s.lineStart,
+func (s *scanner) peekByte() (byte, bool) {
+	b, err := s.input.Peek(1)
+	if err != nil {
+		if err != io.EOF && s.err == nil {
+			s.err = err
+		}
+		return 0, false
 	}
-	s.start = s.pos
+	return b[0], true
 }
 
-func (s *scanner) error(msg string) {
-	s.tokens <- &token{
-		typ:  tokenError,
-		val:  msg,
-		line: s.lineNo,
-		col:  s.start - s.lineStart,
+func (s *scanner) skipWhiteSpace() {
+	for {
+		c, ok := s.peekByte()
+		if !ok || !isSpace(c) {
+			return
+		}
+		s.readByte()
 	}
 }
 
-func (s *scanner) ignore() {
-	s.start = s.pos
+// scanPropIdent scans a property identifier.  Lower case letters are
+// included in the token, so that the parser can deal with FF[3]-style
+// identifiers like "AddBlack".
+func (s *scanner) scanPropIdent(t *token, first byte) {
+	s.buf = append(s.buf[:0], first)
+	for {
+		c, ok := s.peekByte()
+		if !ok || !isLetter(c) {
+			break
+		}
+		s.readByte()
+		s.buf = append(s.buf, c)
+	}
+	t.typ = tokenPropIdent
+	if len(s.buf) <= 2 && isUpper(s.buf) {
+		t.val = s.intern(s.buf)
+	} else {
+		t.val = string(s.buf)
+	}
 }
 
-func (s *scanner) skipWhiteSpace() {
+func (s *scanner) scanPropValue(t *token) {
+	s.buf = s.buf[:0]
+	i := 0 // s.buf[:i] has been checked for escapes and two-byte characters
 	for {
-		r := s.next()
-		if !unicode.IsSpace(r) {
-			// note that eof (rune 0) doesn't count as white space
-			s.backup()
-			s.ignore()
+		chunk, err := s.input.ReadSlice(']')
+		s.buf = append(s.buf, chunk...)
+		s.pos += len(chunk)
+		if n := bytes.Count(chunk, []byte{'\n'}); n > 0 {
+			s.lineNo += n
+			s.lineStart = s.pos - (len(chunk) - 1 - bytes.LastIndexByte(chunk, '\n'))
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		} else if err != nil {
+			if err != io.EOF && s.err == nil {
+				s.err = err
+			}
+			if s.err != nil {
+				t.typ = tokenError
+				t.val = s.err.Error()
+			} else {
+				t.typ = tokenUnterminated
+				t.val = string(s.buf)
+			}
 			return
 		}
+
+		// The closing bracket ends the value, unless it is escaped by a
+		// backslash or is the second byte of a two-byte character.
+		end := len(s.buf) - 1
+		for i < end {
+			if s.buf[i] == '\\' {
+				i++
+			}
+			if i < end && s.lead != nil && s.lead(s.buf[i]) {
+				i++
+			}
+			i++
+		}
+		if i == end {
+			break
+		}
+	}
+
+	t.typ = tokenPropValue
+	val := s.buf[:len(s.buf)-1]
+	if len(val) <= 2 {
+		t.val = s.intern(val)
+	} else {
+		t.val = string(val)
 	}
 }
 
-const eof = rune(0)
+// peekCharset looks ahead in the input for a CA property in the next
+// node, without consuming any input.  The input buffer is enlarged as
+// needed, so that the lookahead can cover the whole root node.  This
+// allows to set up the scanner
+// for the character set of a game tree before the property values of the
+// root node are scanned.  If lead is non-nil, the byte following a lead
+// byte is skipped when looking for the end of a property value.  If no CA
+// property is found, the empty string is returned.
+func (s *scanner) peekCharset(lead func(byte) bool) string {
+	i := 0
+	at := func(i int) (byte, bool) {
+		for {
+			b, err := s.input.Peek(i + 1)
+			if len(b) > i {
+				return b[i], true
+			} else if err != bufio.ErrBufferFull {
+				return 0, false
+			}
+			s.input = bufio.NewReaderSize(s.input, 2*s.input.Size())
+		}
+	}
 
-func scanStart(s *scanner) stateFn {
-	s.skipWhiteSpace()
+	var ident []byte
+	nodes := 0
+	inIdent := false
+	for {
+		c, ok := at(i)
+		if !ok {
+			return ""
+		}
+		i++
 
-	r := s.next()
-	switch {
-	case r == eof:
-		s.emit(tokenEOF)
-		return nil
-	case r == '(':
-		s.emit(tokenParenOpen)
-	case r == ')':
-		s.emit(tokenParenClose)
-	case r == '[':
-		return scanPropValue
-	case r == ';':
-		s.emit(tokenSemicolon)
-	case r >= 'A' && r <= 'Z':
-		s.backup()
-		return scanPropIdent
-	default:
-		s.error(fmt.Sprintf("unexpected character %q", r))
+		isIdent := isLetter(c)
+		switch {
+		case isIdent && !inIdent:
+			ident = append(ident[:0], c)
+		case isIdent:
+			ident = append(ident, c)
+		case c == ';':
+			nodes++
+			if nodes > 1 {
+				return ""
+			}
+		case c == '(' || c == ')':
+			return ""
+		case c == '[':
+			start := i
+			for {
+				c, ok = at(i)
+				if !ok {
+					return ""
+				}
+				i++
+				if c == '\\' || lead != nil && lead(c) {
+					i++
+				} else if c == ']' {
+					break
+				}
+			}
+			if string(ident) == "CA" {
+				b, _ := s.input.Peek(i - 1)
+				return string(b[start:])
+			}
+		}
+		inIdent = isIdent
 	}
+}
 
-	return scanStart
+// isHighByte reports whether c is outside the ASCII range.  All two-byte
+// characters of the usual legacy encodings start with such a byte.
+func isHighByte(c byte) bool {
+	return c >= 0x80
 }
 
-func scanPropIdent(s *scanner) stateFn {
+// scanInvalid scans a run of characters which cannot start a token.
+func (s *scanner) scanInvalid(t *token, first byte) {
+	s.buf = append(s.buf[:0], first)
 	for {
-		r := s.next()
-		if r < 'A' || r > 'Z' {
-			s.backup()
-			s.emit(tokenPropIdent)
-			return scanStart
+		c, ok := s.peekByte()
+		if !ok || isSpace(c) || isLetter(c) || strings.IndexByte("();[", c) >= 0 {
+			break
 		}
+		s.readByte()
+		s.buf = append(s.buf, c)
 	}
+	t.typ = tokenInvalid
+	t.val = string(s.buf)
 }
 
-func scanPropValue(s *scanner) stateFn {
-	s.ignore()
-	escaped := false
-	for {
-		r := s.next()
-		if escaped {
-			escaped = false
-			continue
-		} else if r == '\\' {
-			escaped = true
-			continue
-		} else if r == ']' {
-			s.backup()
-			s.emit(tokenPropValue)
-			s.next()
-			return scanStart
-		} else if r == eof {
-			s.error("EOF while scanning PropValue")
-			return nil
+// intern returns a string with the contents of b, reusing earlier copies
+// where possible.
+func (s *scanner) intern(b []byte) string {
+	if str, ok := s.interned[string(b)]; ok {
+		return str
+	}
+	str := string(b)
+	s.interned[str] = str
+	return str
+}
+
+// isUpper reports whether b consists of upper case ASCII letters only.
+func isUpper(b []byte) bool {
+	for _, c := range b {
+		if c < 'A' || c > 'Z' {
+			return false
 		}
 	}
+	return true
+}
+
+// isLetter reports whether c is an ASCII letter.
+func isLetter(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// isSpace reports whether c is an ASCII white space character.
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
 }
 
 type tokenType int
 
 const (
-	tokenError tokenType = iota
+	tokenError tokenType = iota // a read error
 	tokenEOF
 	tokenParenOpen
 	tokenParenClose
 	tokenSemicolon
 	tokenPropIdent
 	tokenPropValue
+	tokenUnterminated // a property value cut off by the end of input
+	tokenInvalid      // characters which cannot start a token
 )
 
 type token struct {
 	typ  tokenType
 	val  string
+	pos  int // byte offset, 0 based
 	line int // 0 based
 	col  int // 0 based
 }
 
 func (i token) String() string {
-	t := i.typ
-	if t == tokenEOF {
+	switch i.typ {
+	case tokenEOF:
 		return "EOF"
+	case tokenParenOpen:
+		return "("
+	case tokenParenClose:
+		return ")"
+	case tokenSemicolon:
+		return ";"
 	}
 
 	v := i.val
-	if t != tokenError && len(i.val) > 10 {
+	if i.typ != tokenError && len(i.val) > 10 {
 		v = fmt.Sprintf("%.10s...", v)
 	}
 	return v
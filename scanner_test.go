@@ -17,43 +17,75 @@
 package sgf
 
 import (
+	"fmt"
+	"strings"
 	"testing"
-	"unicode"
 )
 
-func TestEOF(t *testing.T) {
-	if unicode.IsSpace(eof) {
-		t.Error("eof cannot be a space character")
+func TestScanner(t *testing.T) {
+	in := "(;B[aa]\nC[x\n\\]y]\n  ;W[])"
+	cases := []token{
+		{tokenParenOpen, "", 0, 0, 0},
+		{tokenSemicolon, "", 1, 0, 1},
+		{tokenPropIdent, "B", 2, 0, 2},
+		{tokenPropValue, "aa", 3, 0, 3},
+		{tokenPropIdent, "C", 8, 1, 0},
+		{tokenPropValue, "x\n\\]y", 9, 1, 1},
+		{tokenSemicolon, "", 19, 3, 2},
+		{tokenPropIdent, "W", 20, 3, 3},
+		{tokenPropValue, "", 21, 3, 4},
+		{tokenParenClose, "", 23, 3, 6},
+		{tokenEOF, "", 24, 3, 7},
+		{tokenEOF, "", 24, 3, 7},
 	}
-}
 
-func TestScanner(t *testing.T) {
-	s := scanner{
-		input: "a\n12\n",
+	s := newScanner(strings.NewReader(in))
+	for i, test := range cases {
+		tok := s.next()
+		if tok != test {
+			t.Errorf("%d: expected %#v, got %#v", i, test, tok)
+		}
 	}
+}
 
+func TestScannerErrors(t *testing.T) {
 	cases := []struct {
-		r         rune
-		lineNo    int
-		lineStart int
+		in  string
+		typ tokenType
+		val string
 	}{
-		{'a', 0, 0},
-		{'\n', 0, 0},
-		{'1', 1, 2},
-		{'2', 1, 2},
-		{'\n', 1, 2},
-		{eof, 2, 5},
+		{"(;B[aa", tokenUnterminated, "aa"},
+		{"(;B[aa\\]", tokenUnterminated, "aa\\]"},
+		{"(;AddBlack[aa])", tokenPropIdent, "AddBlack"},
+		{"(;B[aa]%$)", tokenInvalid, "%$"},
 	}
 	for _, test := range cases {
-		r := s.next()
-		if r != test.r {
-			t.Errorf("expected %q, got %q", test.r, r)
-		}
-		if s.lineNo != test.lineNo {
-			t.Errorf("expected lineNo %d, got %d", test.lineNo, s.lineNo)
-		}
-		if s.lineStart != test.lineStart {
-			t.Errorf("expected lineStart %d, got %d", test.lineStart, s.lineStart)
+		s := newScanner(strings.NewReader(test.in))
+		for {
+			tok := s.next()
+			if tok.typ == test.typ {
+				if tok.val != test.val {
+					t.Errorf("%q: expected %q, got %q", test.in, test.val, tok.val)
+				}
+				break
+			} else if tok.typ == tokenEOF {
+				t.Errorf("%q: token not found", test.in)
+				break
+			}
 		}
 	}
 }
+
+func TestScannerInternBounded(t *testing.T) {
+	var in strings.Builder
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&in, "junk%d (;B[aa]C[comment %d]ABC[x]) ", i, i)
+	}
+	s := newScanner(strings.NewReader(in.String()))
+	for s.next().typ != tokenEOF {
+	}
+	// only "B", "C", "aa" and "x" are short enough to be interned
+	if len(s.interned) != 4 {
+		t.Errorf("interned %d strings: %v", len(s.interned), s.interned)
+	}
+}
@@ -0,0 +1,285 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrResultMismatch indicates that the result given in the RE property
+// does not agree with the score counted on the board.
+var ErrResultMismatch = errors.New("result does not match the board")
+
+// A Score gives the result of counting a finished game.
+type Score struct {
+	// Scoring is the method used to count the score.
+	Scoring ScoringMethod
+
+	// BlackTerritory and WhiteTerritory give the number of empty points
+	// (including points occupied by dead stones) surrounded only by
+	// stones of one colour.
+	BlackTerritory, WhiteTerritory int
+
+	// BlackStones and WhiteStones give the number of live stones on the
+	// board.  These are counted for area scoring only.
+	BlackStones, WhiteStones int
+
+	// BlackPrisoners and WhitePrisoners give the number of stones
+	// captured by each player, including dead stones removed at the end
+	// of the game.  These are counted for territory scoring only.
+	BlackPrisoners, WhitePrisoners int
+
+	// Komi is the number of points given to white.
+	Komi float64
+
+	// Compensation is the number of points given to white for handicap
+	// stones, in addition to komi.
+	Compensation int
+
+	// Black and White are the total scores of the two players.
+	Black, White float64
+}
+
+// Margin returns the number of points by which black is ahead.  The
+// margin is negative if white wins.
+func (s *Score) Margin() float64 {
+	return s.Black - s.White
+}
+
+// Winner returns the color of the winning player, or Empty for a draw.
+func (s *Score) Winner() Color {
+	switch m := s.Margin(); {
+	case m > 0:
+		return Black
+	case m < 0:
+		return White
+	default:
+		return Empty
+	}
+}
+
+// Result returns the outcome of the game, as used for the RE property.
+func (s *Score) Result() Result {
+	switch w := s.Winner(); w {
+	case Black, White:
+		return Result{Kind: ResultWin, Winner: w, Reason: ReasonScore, Margin: math.Abs(s.Margin())}
+	default:
+		return Result{Kind: ResultDraw}
+	}
+}
+
+func (s *Score) String() string {
+	return s.Result().String()
+}
+
+// Score counts the board as a finished game, after removing the stones
+// listed in dead.  Empty points which are surrounded only by stones of
+// one colour are counted as territory of that colour; all other empty
+// points are neutral.  Points in dead which are not occupied by a stone
+// are ignored.  The board is not modified.
+//
+// White receives komi, and the handicap compensation given by rules for
+// the given number of handicap stones.  No special treatment is given to
+// points in seki.
+func (b *Board) Score(rules Rules, dead []Move, komi float64, handicap int) *Score {
+	stones := append([]Color(nil), b.stones...)
+	s := &Score{
+		Scoring:        rules.Scoring,
+		BlackPrisoners: b.captures[Black],
+		WhitePrisoners: b.captures[White],
+		Komi:           komi,
+		Compensation:   rules.Compensation.points(handicap),
+	}
+	for _, p := range dead {
+		if !b.Size.contains(p) {
+			continue
+		}
+		idx := b.index(p)
+		switch stones[idx] {
+		case Black:
+			s.WhitePrisoners++
+		case White:
+			s.BlackPrisoners++
+		}
+		stones[idx] = Empty
+	}
+
+	seen := make([]bool, len(stones))
+	var region []int
+	for i, c := range stones {
+		switch {
+		case c == Black:
+			s.BlackStones++
+			continue
+		case c == White:
+			s.WhiteStones++
+			continue
+		case seen[i]:
+			continue
+		}
+
+		// flood fill the empty region containing i
+		var borders [3]bool
+		seen[i] = true
+		region = append(region[:0], i)
+		for k := 0; k < len(region); k++ {
+			b.forNeighbours(region[k], func(j int) {
+				if stones[j] != Empty {
+					borders[stones[j]] = true
+				} else if !seen[j] {
+					seen[j] = true
+					region = append(region, j)
+				}
+			})
+		}
+		switch {
+		case borders[Black] && !borders[White]:
+			s.BlackTerritory += len(region)
+		case borders[White] && !borders[Black]:
+			s.WhiteTerritory += len(region)
+		}
+	}
+
+	black := s.BlackTerritory
+	white := s.WhiteTerritory
+	if rules.Scoring == AreaScoring {
+		black += s.BlackStones
+		white += s.WhiteStones
+	} else {
+		black += s.BlackPrisoners
+		white += s.WhitePrisoners
+	}
+	s.Black = float64(black)
+	s.White = float64(white) + komi + float64(s.Compensation)
+	return s
+}
+
+// ScoreGame counts the final position of the main variation of the game
+// tree with root t.  The rules are taken from the RU property, defaulting
+// to territory scoring if RU is missing or names a rule set not known to
+// RulesByName; komi is taken from KM, and the number of handicap stones
+// from HA.  These properties are read from the node found by
+// GameInfoNode.  If dead is nil, black stones inside the territory marked
+// by TW and white stones inside the territory marked by TB at the last
+// node of the main variation are taken to be dead.
+func (t *Tree) ScoreGame(dead []Move) (*Score, error) {
+	info := t.GameInfoNode()
+	if info == nil {
+		info = t
+	}
+	rules, err := info.GetRules()
+	if err != nil {
+		// RU is free text, so unknown rule sets are not an error
+		rules = Rules{}
+	}
+	komi, err := info.GetRealDefault("KM", 0)
+	if err != nil {
+		return nil, info.setNode(err)
+	}
+	handicap, err := info.GetNumberDefault("HA", 0)
+	if err != nil {
+		return nil, info.setNode(err)
+	}
+
+	pos, err := t.StartPosition()
+	if err != nil {
+		return nil, err
+	}
+	pos.Board.Rules = rules
+	n := t
+	for {
+		err = pos.Apply(n)
+		if err != nil {
+			return nil, err
+		}
+		if len(n.Children) == 0 {
+			break
+		}
+		n = n.Children[0]
+	}
+
+	if dead == nil {
+		dead, err = n.markedDeadStones(pos.Board)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pos.Board.Score(rules, dead, komi, handicap), nil
+}
+
+// markedDeadStones returns the stones on b which lie inside the territory
+// of the opponent, as marked by the TB and TW properties of t.
+func (t *Tree) markedDeadStones(b *Board) ([]Move, error) {
+	var dead []Move
+	for _, c := range []Color{Black, White} {
+		name := "T" + c.propertyName()
+		if _, ok := t.Properties[name]; !ok {
+			continue
+		}
+		pts, err := t.Properties.GetPointList(name, b.Size)
+		if err != nil {
+			return nil, t.setNode(err)
+		}
+		for _, p := range pts {
+			if b.At(p) == c.Opponent() {
+				dead = append(dead, p)
+			}
+		}
+	}
+	return dead, nil
+}
+
+// CheckResult compares the score s with the result given in the RE
+// property of the game with root t, as found by GameInfoNode.  If the
+// result disagrees with the score, an error wrapping ErrResultMismatch
+// is returned.  Results which were not decided by counting, like
+// resignations, and missing RE properties are not checked.  If a winner
+// is given without a margin, only the winner is compared.
+func (t *Tree) CheckResult(s *Score) error {
+	info := t.GameInfoNode()
+	if info == nil {
+		return nil
+	}
+	res, err := info.GetResult()
+	if errors.Is(err, ErrMissingProperty) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	counted := s.Result()
+	var ok bool
+	switch {
+	case res.Kind == ResultDraw:
+		ok = counted.Kind == ResultDraw
+	case res.Kind != ResultWin:
+		ok = true
+	case res.Reason == ReasonScore:
+		ok = res == counted
+	case res.Reason == ReasonUnspecified:
+		ok = res.Winner == counted.Winner
+	default:
+		ok = true
+	}
+	if !ok {
+		err := fmt.Errorf("%w (counted %s)", ErrResultMismatch, counted)
+		return info.setNode(newPropertyError("RE", info.Properties["RE"][0], err))
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestBoardScore(t *testing.T) {
+	b := boardFromDiagram(t, `
+		.XO.X
+		.XO..
+		.XO..
+		.XO..
+		.XO..`)
+	b.captures[Black] = 2
+	dead := []Move{{4, 4}}
+
+	area := b.Score(Rules{Scoring: AreaScoring}, dead, 7.5, 0)
+	if area.BlackTerritory != 5 || area.WhiteTerritory != 10 ||
+		area.BlackStones != 5 || area.WhiteStones != 5 {
+		t.Errorf("wrong area count: %+v", area)
+	}
+	if area.Black != 10 || area.White != 22.5 || area.Winner() != White {
+		t.Errorf("wrong area score: %+v", area)
+	}
+
+	territory := b.Score(Rules{}, dead, 6.5, 0)
+	if territory.BlackPrisoners != 2 || territory.WhitePrisoners != 1 {
+		t.Errorf("wrong prisoners: %+v", territory)
+	}
+	if territory.Black != 7 || territory.White != 17.5 {
+		t.Errorf("wrong territory score: %+v", territory)
+	}
+	if s := territory.String(); s != "W+10.5" {
+		t.Errorf("wrong result string %q", s)
+	}
+
+	if b.At(Move{4, 4}) != Black {
+		t.Error("board was modified")
+	}
+}
+
+func TestBoardScoreNeutral(t *testing.T) {
+	b := boardFromDiagram(t, "X.O")
+	s := b.Score(Rules{Scoring: AreaScoring}, nil, 0, 0)
+	if s.BlackTerritory != 0 || s.WhiteTerritory != 0 || s.Winner() != Empty {
+		t.Errorf("wrong score: %+v", s)
+	}
+	if s.String() != "0" {
+		t.Errorf("wrong result string %q", s.String())
+	}
+}
+
+func TestHandicapCompensation(t *testing.T) {
+	b := NewBoard(BoardSize{9, 9})
+	for name, expected := range map[string]int{"Japanese": 0, "Chinese": 4, "AGA": 3} {
+		rules, _ := RulesByName(name)
+		s := b.Score(rules, nil, 0.5, 4)
+		if s.Compensation != expected || s.White != 0.5+float64(expected) {
+			t.Errorf("%s: wrong compensation %d", name, s.Compensation)
+		}
+	}
+	rules, _ := RulesByName("Chinese")
+	if s := b.Score(rules, nil, 0.5, 1); s.Compensation != 0 {
+		t.Errorf("compensation %d for one handicap stone", s.Compensation)
+	}
+}
+
+func TestScoreGame(t *testing.T) {
+	const game = "(;SZ[5]KM[0.5]RU[Chinese]AB[ba:be][ea]AW[ca:ce]RE[%s];TW[da:ee])"
+	cases := []struct {
+		result string
+		err    error
+	}{
+		{"W+5.5", nil},
+		{"W+R", nil},
+		{"W+", nil},
+		{"?", nil},
+		{"B+3", ErrResultMismatch},
+		{"W+3.5", ErrResultMismatch},
+		{"0", ErrResultMismatch},
+	}
+	for _, test := range cases {
+		tree := readTree(t, fmt.Sprintf(game, test.result))
+		s, err := tree.ScoreGame(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Black != 10 || s.White != 15.5 {
+			t.Fatalf("wrong score: %+v", s)
+		}
+		err = tree.CheckResult(s)
+		if !errors.Is(err, test.err) {
+			t.Errorf("RE[%s]: expected %v, got %v", test.result, test.err, err)
+		}
+	}
+}
+
+func TestScoreGameInfoNode(t *testing.T) {
+	// unknown rules default to territory scoring, and the game-info
+	// properties are read from the second node
+	tree := readTree(t, "(;SZ[5]AB[ba:be][ea]AW[ca:ce];RU[Japanese 1989]KM[0.5]RE[W+6.5];TW[da:ee])")
+	s, err := tree.ScoreGame(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Black != 5 || s.White != 11.5 {
+		t.Errorf("wrong score: %+v", s)
+	}
+	if err := tree.CheckResult(s); err != nil {
+		t.Error(err)
+	}
+}
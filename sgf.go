@@ -16,10 +16,6 @@
 
 package sgf
 
-import (
-	"fmt"
-)
-
 // A Collection is a slice of game trees.
 type Collection []*Tree
 
@@ -70,29 +66,21 @@ func (t *Tree) MainVariationMoves() ([]Move, error) {
 	}
 
 	var res []Move
-	next := 'B'
+	next := Black
 	for {
-		props := t.Properties
-		black, bOk := props["B"]
-		white, wOk := props["W"]
-
-		if bOk && wOk {
-			return nil, newErrorf("both B and W are set")
-		} else if bOk {
-			if next != 'B' {
-				return nil, newErrorf("black played out of turn")
-			}
-			res = append(res, b.DecodeMove(black[0]))
-			next = 'W'
-		} else if wOk {
-			if next != 'W' {
-				return nil, newErrorf("white played out of turn")
+		c, m, err := t.getMove(b)
+		if err != nil {
+			return nil, t.setNode(err)
+		}
+		if c != Empty {
+			if c != next {
+				name := c.propertyName()
+				return nil, t.setNode(newPropertyError(name, t.Properties[name][0], ErrOutOfTurn))
 			}
-			res = append(res, b.DecodeMove(white[0]))
-			next = 'B'
+			res = append(res, m)
+			next = c.Opponent()
 		}
 
-		res = append(res)
 		if len(t.Children) == 0 {
 			break
 		}
@@ -100,15 +88,3 @@ func (t *Tree) MainVariationMoves() ([]Move, error) {
 	}
 	return res, nil
 }
-
-type sgfError struct {
-	msg string
-}
-
-func (e *sgfError) Error() string {
-	return e.msg
-}
-
-func newErrorf(format string, args ...interface{}) error {
-	return &sgfError{fmt.Sprintf(format, args...)}
-}
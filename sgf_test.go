@@ -18,7 +18,9 @@ package sgf
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 
@@ -61,6 +63,123 @@ func TestSimpleText(t *testing.T) {
 	}
 }
 
+func TestText(t *testing.T) {
+	cases := []struct {
+		in  string
+		out string
+	}{
+		{"", ""},
+		{"a", "a"},
+		{"a\nb", "a\nb"},
+		{"a\rb", "a\nb"},
+		{"a\n\rb", "a\nb"},
+		{"a\r\nb", "a\nb"},
+		{"a\n\nb", "a\n\nb"},
+		{"a\r\n\r\nb", "a\n\nb"},
+		{"a[\\]b", "a[]b"},
+		{"a\\:b", "a:b"},
+		{"a\\\\b", "a\\b"},
+		{"a\\\nb", "ab"},
+		{"a\\\rb", "ab"},
+		{"a\\\n\rb", "ab"},
+		{"a\\\r\nb", "ab"},
+		{"a\\\n\nb", "a\nb"},
+		{"a \t b", "a   b"},
+		{"a\\\tb", "a b"},
+	}
+	for i, test := range cases {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			n := Properties{
+				"TEST": []string{test.in},
+			}
+			got, err := n.GetText("TEST")
+			if err != nil {
+				t.Error(err)
+			}
+			if got != test.out {
+				t.Errorf("text(%q) = %q, want %q", test.in, got, test.out)
+			}
+		})
+	}
+}
+
+func TestSetText(t *testing.T) {
+	cases := []string{
+		"",
+		"hello",
+		"line 1\nline 2\n\nline 4\n",
+		"a]b\\c:d",
+		"ends in a backslash\\",
+		"backslash\\\nbefore newline",
+	}
+	for _, in := range cases {
+		n := Properties{}
+		n.SetText("C", in)
+		out, err := n.GetText("C")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != in {
+			t.Errorf("GetText(SetText(%q)) = %q", in, out)
+		}
+	}
+
+	n := Properties{}
+	n.SetText("C", "a\r\nb\rc")
+	if n["C"][0] != "a\nb\nc" {
+		t.Errorf("line breaks not normalised: %q", n["C"][0])
+	}
+	n.SetSimpleText("GN", "a]\r\nb")
+	if n["GN"][0] != "a\\] b" {
+		t.Errorf("wrong simple text value: %q", n["GN"][0])
+	}
+	gn, _ := n.GetSimpleText("GN")
+	if gn != "a] b" {
+		t.Errorf("GetSimpleText(SetSimpleText(...)) = %q", gn)
+	}
+}
+
+func TestSetValues(t *testing.T) {
+	n := Properties{}
+	n.SetValues("C", "see a]b", `back\slash`, "a:b")
+	n.SetCompose("LB", "aa", "x:]y")
+	n.SetNumber("HA", -3)
+	n.SetReal("KM", 6.5)
+
+	expected := Properties{
+		"C":  []string{`see a\]b`, `back\\slash`, "a:b"},
+		"LB": []string{`aa:x\:\]y`},
+		"HA": []string{"-3"},
+		"KM": []string{"6.5"},
+	}
+	if d := cmp.Diff(expected, n); d != "" {
+		t.Errorf("unexpected raw values (-want +got):\n%s", d)
+	}
+
+	a, b, ok := splitCompose(n["LB"][0])
+	if !ok || a != "aa" || b != `x\:\]y` {
+		t.Errorf("splitCompose: got %q, %q, %t", a, b, ok)
+	}
+}
+
+func TestWriteInvalid(t *testing.T) {
+	cases := []Properties{
+		{"C": []string{"a]b"}},
+		{"C": []string{`a\`}},
+		{"C": []string{}},
+		{"c": []string{"x"}},
+		{"": []string{"x"}},
+	}
+	for _, props := range cases {
+		c := Collection{{Properties: props}}
+		err := c.Write(io.Discard)
+		var pe *PropertyError
+		if !errors.As(err, &pe) {
+			t.Errorf("%q: expected PropertyError, got %v", props, err)
+		}
+	}
+}
+
 func TestExamples(t *testing.T) {
 	for _, test := range examples {
 		r := strings.NewReader(test)
@@ -107,6 +226,50 @@ func FuzzSGF(f *testing.F) {
 	})
 }
 
+func FuzzSetters(f *testing.F) {
+	f.Add("a", "b")
+	f.Add("see a]b", "x:y")
+	f.Add(`a\`, `\]`)
+	f.Fuzz(func(t *testing.T, a, b string) {
+		n := Properties{}
+		n.SetValues("C", a, b)
+		n.SetCompose("LB", a, b)
+		if got := unescapeForTest(n["C"][0]); got != a {
+			t.Errorf("SetValues(%q) decoded to %q", a, got)
+		}
+		rawA, rawB, ok := splitCompose(n["LB"][0])
+		if !ok || unescapeForTest(rawA) != a || unescapeForTest(rawB) != b {
+			t.Errorf("SetCompose(%q, %q) gave %q", a, b, n["LB"][0])
+		}
+
+		c1 := Collection{{Properties: n}}
+		buf := &bytes.Buffer{}
+		err := c1.Write(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c2, err := Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := cmp.Diff(c1, c2); d != "" {
+			t.Errorf("Read(Write(c)) mismatch (-want +got):\n%s", d)
+		}
+	})
+}
+
+// unescapeForTest removes the escape characters from a raw value.
+func unescapeForTest(raw string) string {
+	var res []byte
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '\\' && i+1 < len(raw) {
+			i++
+		}
+		res = append(res, raw[i])
+	}
+	return string(res)
+}
+
 var examples = []string{
 	`(;FF[4]C[root](;C[a];C[b](;C[c])
 (;C[d];C[e]))
@@ -0,0 +1,189 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+// A Transposition is a position, together with the player to move, which
+// is reached at several nodes of a game tree.
+type Transposition struct {
+	// Hash is the situational hash of the position, see
+	// Position.SituationalHash.
+	Hash uint64
+
+	// Nodes lists the nodes where the position is reached, in depth-first
+	// order.  Paths gives the corresponding paths.
+	Nodes []*Tree
+	Paths []Path
+}
+
+// FindTranspositions replays all variations of the game tree with root t,
+// and returns all positions which are reached, with the same player to
+// move, at more than one node.  Positions are compared using Zobrist
+// hashes.  A node which repeats a position of one of its ancestors, for
+// example because it contains no move or because of passes, is not
+// counted as a separate occurrence.  The transpositions are listed in the
+// order in which their positions first occur.
+//
+// If an invalid property or an illegal move is found, a *MoveError is
+// returned.
+func (t *Tree) FindTranspositions() ([]*Transposition, error) {
+	groups, err := t.positionGroups()
+	if err != nil {
+		return nil, err
+	}
+	var res []*Transposition
+	for _, g := range groups {
+		if len(g.Nodes) > 1 {
+			res = append(res, g)
+		}
+	}
+	return res, nil
+}
+
+// positionGroups groups the nodes of the tree by position, in the order
+// in which the positions first occur.
+func (t *Tree) positionGroups() ([]*Transposition, error) {
+	start, err := t.StartPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	type task struct {
+		node *Tree
+		pos  *Position // the position before node is applied
+		path Path
+		key  uint64 // ancestor entry to remove, for the exit marker
+		exit bool
+	}
+
+	var groups []*Transposition
+	byHash := make(map[uint64]*Transposition)
+	ancestors := make(map[uint64]int)
+	stack := []task{{node: t, pos: start}}
+	for len(stack) > 0 {
+		tk := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if tk.exit {
+			ancestors[tk.key]--
+			continue
+		}
+
+		n, pos := tk.node, tk.pos
+		err := pos.Apply(n)
+		if err != nil {
+			return nil, &MoveError{Path: tk.path, Err: err}
+		}
+		key := pos.SituationalHash()
+		if ancestors[key] == 0 {
+			g := byHash[key]
+			if g == nil {
+				g = &Transposition{Hash: key}
+				byHash[key] = g
+				groups = append(groups, g)
+			}
+			g.Nodes = append(g.Nodes, n)
+			g.Paths = append(g.Paths, tk.path)
+		}
+		ancestors[key]++
+		stack = append(stack, task{key: key, exit: true})
+
+		for i := len(n.Children) - 1; i >= 0; i-- {
+			childPos := pos
+			if i > 0 {
+				childPos = pos.Clone()
+			}
+			stack = append(stack, task{node: n.Children[i], pos: childPos, path: tk.path.child(i)})
+		}
+	}
+	return groups, nil
+}
+
+// A TranspositionIndex identifies the nodes of a game tree which lead to
+// the same position.  This allows to treat the tree as a directed acyclic
+// graph, where all continuations of a position are shared between the
+// nodes which reach this position.
+type TranspositionIndex struct {
+	size   BoardSize
+	byNode map[*Tree]*Transposition
+}
+
+// IndexTranspositions builds a TranspositionIndex for the game tree with
+// root t.  Positions are identified as described for FindTranspositions.
+// The index becomes invalid if the tree is modified.
+func (t *Tree) IndexTranspositions() (*TranspositionIndex, error) {
+	sz, err := t.GetBoardSize()
+	if err != nil {
+		return nil, err
+	}
+	groups, err := t.positionGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &TranspositionIndex{
+		size:   sz,
+		byNode: make(map[*Tree]*Transposition),
+	}
+	for _, g := range groups {
+		if len(g.Nodes) < 2 {
+			continue
+		}
+		for _, n := range g.Nodes {
+			idx.byNode[n] = g
+		}
+	}
+	return idx, nil
+}
+
+// Lookup returns the transposition the node n is part of.  If the
+// position at n is not reached at any other node, nil is returned.
+func (idx *TranspositionIndex) Lookup(n *Tree) *Transposition {
+	return idx.byNode[n]
+}
+
+// Canonical returns the first node, in depth-first order, where the
+// position at n is reached.  If the position at n is not reached at any
+// other node, n itself is returned.
+func (idx *TranspositionIndex) Canonical(n *Tree) *Tree {
+	if g := idx.byNode[n]; g != nil {
+		return g.Nodes[0]
+	}
+	return n
+}
+
+// Continuations returns the children of all nodes where the position at
+// n is reached.  Children with the same move are only listed once, the
+// first occurrence in depth-first order being used.  The returned slice
+// is newly allocated and can be modified by the caller.
+func (idx *TranspositionIndex) Continuations(n *Tree) []*Tree {
+	g := idx.byNode[n]
+	if g == nil {
+		return append([]*Tree(nil), n.Children...)
+	}
+
+	var res []*Tree
+	seen := make(map[string]bool)
+	for _, m := range g.Nodes {
+		for _, child := range m.Children {
+			key := child.mergeKey(idx.size, false)
+			if !seen[key] {
+				seen[key] = true
+				res = append(res, child)
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,110 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFindTranspositions(t *testing.T) {
+	tree := readTree(t, `(;SZ[9]
+		(;B[cc];W[gg];B[cg];W[gc];B[ee])
+		(;B[cg];W[gc];B[cc];W[gg];B[dd])
+		(;B[cc];W[gg];C[comment];B[ee])
+		(;W[gg];B[cc]))`)
+
+	ts, err := tree.FindTranspositions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		{"1", "0.2"},
+		{"2", "0.2.1"},
+		{"4", "0.1.3"},
+	}
+	if len(ts) != len(expected) {
+		t.Fatalf("expected %d transpositions, got %d", len(expected), len(ts))
+	}
+	for i, tr := range ts {
+		if len(tr.Paths) != len(expected[i]) || len(tr.Nodes) != len(tr.Paths) {
+			t.Errorf("%d: wrong paths %v", i, tr.Paths)
+			continue
+		}
+		for j, p := range tr.Paths {
+			if p.String() != expected[i][j] {
+				t.Errorf("%d.%d: expected path %s, got %s", i, j, expected[i][j], p)
+			}
+			if n, _ := p.Resolve(tree); n != tr.Nodes[j] {
+				t.Errorf("%d.%d: wrong node", i, j)
+			}
+		}
+	}
+}
+
+func TestTranspositionIndex(t *testing.T) {
+	tree := readTree(t, `(;SZ[9]
+		(;B[cc];W[gg];B[cg];W[gc];B[ee])
+		(;B[cg];W[gc];B[cc](;W[gg];B[dd])(;W[gg];B[ee]))
+		(;B[ff]))`)
+	idx, err := tree.IndexTranspositions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := Path{0, 0, 0, 0}.Resolve(tree)
+	second, _ := Path{1, 0, 0, 0}.Resolve(tree)
+	if idx.Canonical(second) != first || idx.Canonical(first) != first {
+		t.Error("wrong canonical node")
+	}
+	if tr := idx.Lookup(second); tr == nil || len(tr.Nodes) != 3 {
+		t.Errorf("wrong transposition %v", tr)
+	}
+
+	var moves []string
+	for _, n := range idx.Continuations(second) {
+		moves = append(moves, moveOf(n))
+	}
+	if len(moves) != 2 || moves[0] != "Bee" || moves[1] != "Bdd" {
+		t.Errorf("wrong continuations %v", moves)
+	}
+
+	single, _ := Path{2}.Resolve(tree)
+	if idx.Lookup(single) != nil || idx.Canonical(single) != single ||
+		len(idx.Continuations(single)) != 0 {
+		t.Error("node without transposition misreported")
+	}
+
+	// the result must not share memory with the tree
+	cont := idx.Continuations(tree)
+	if len(cont) != 3 {
+		t.Fatalf("wrong continuations of the root %v", cont)
+	}
+	cont[0] = nil
+	if tree.Children[0] == nil {
+		t.Error("Continuations returned the Children slice of the tree")
+	}
+}
+
+func TestFindTranspositionsIllegal(t *testing.T) {
+	tree := readTree(t, "(;SZ[9];B[cc];W[cc])")
+	_, err := tree.FindTranspositions()
+	var merr *MoveError
+	if !errors.As(err, &merr) || !errors.Is(err, ErrOccupied) || merr.Path.String() != "2" {
+		t.Errorf("expected MoveError for occupied point, got %v", err)
+	}
+}
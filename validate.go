@@ -0,0 +1,118 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSuperko indicates a move which violates the superko rule.
+var ErrSuperko = errors.New("superko violation")
+
+// A MoveError describes a problem found by Tree.CheckMoves.
+type MoveError struct {
+	// Path gives the node with the problem.
+	Path Path
+
+	// Err describes the problem.  This is normally a *PropertyError,
+	// wrapping one of ErrOccupied, ErrSuicide, ErrKo, ErrSuperko or,
+	// for malformed properties, ErrInvalidValue and similar.
+	Err error
+}
+
+func (e *MoveError) Error() string {
+	return fmt.Sprintf("node %s: %v", e.Path, e.Err)
+}
+
+func (e *MoveError) Unwrap() error {
+	return e.Err
+}
+
+// CheckMoves replays all variations of the game tree with the given rules,
+// and reports all illegal moves and invalid setup or move properties.
+// Repeated positions are detected using Zobrist hashes, see Position.Hash.
+// To check a game using the rules given in the RU property, use
+// Tree.GetRules to obtain the rules.
+// After a problem has been found, the subtree below the offending node
+// is not checked further.  An error is returned if the SZ property
+// of the root node is invalid.
+func (t *Tree) CheckMoves(rules Rules) ([]*MoveError, error) {
+	start, err := t.StartPosition()
+	if err != nil {
+		return nil, err
+	}
+	start.Board.Rules = rules
+
+	type task struct {
+		node *Tree
+		pos  *Position // the position before node is applied
+		path Path
+		key  uint64 // history entry to remove, for the exit marker
+		exit bool
+	}
+
+	var res []*MoveError
+	history := make(map[uint64]int)
+	stack := []task{{node: t, pos: start}}
+	for len(stack) > 0 {
+		tk := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if tk.exit {
+			history[tk.key]--
+			continue
+		}
+
+		n, pos := tk.node, tk.pos
+		c, m, _ := n.getMove(pos.Board.Size)
+		err := pos.Apply(n)
+		if err == nil && rules.Superko != NoSuperko {
+			key := pos.key(rules.Superko)
+			if c != Empty && !m.IsPass() && history[key] > 0 {
+				err = n.setNode(newPropertyError(c.propertyName(), n.Properties[c.propertyName()][0], ErrSuperko))
+			} else {
+				history[key]++
+				stack = append(stack, task{key: key, exit: true})
+			}
+		}
+		if err != nil {
+			res = append(res, &MoveError{Path: tk.path, Err: err})
+			continue
+		}
+
+		for i := len(n.Children) - 1; i >= 0; i-- {
+			childPos := pos
+			if i > 0 {
+				childPos = pos.Clone()
+			}
+			childPath := make(Path, len(tk.path)+1)
+			copy(childPath, tk.path)
+			childPath[len(tk.path)] = i
+			stack = append(stack, task{node: n.Children[i], pos: childPos, path: childPath})
+		}
+	}
+	return res, nil
+}
+
+// key returns the hash used to detect repetitions of the position under
+// the given superko rule.
+func (p *Position) key(rule SuperkoRule) uint64 {
+	if rule == SituationalSuperko {
+		return p.SituationalHash()
+	}
+	return p.Hash()
+}
@@ -0,0 +1,102 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheckMoves(t *testing.T) {
+	tree := readTree(t, `(;SZ[5:4]AB[ba][ab][bc]AW[ca][bb][db][cc]
+		(;B[cb];W[];B[];W[bb];B[dd])
+		(;B[cb];W[bb])
+		(;B[ca])
+		(;B[dd];W[ea];B[ec];W[ed])
+		(;B[zz];W[dd]))`)
+
+	type problem struct {
+		path Path
+		err  error
+	}
+	cases := []struct {
+		rules    Rules
+		problems []problem
+	}{
+		{
+			rules: Rules{},
+			problems: []problem{
+				{[]int{1, 0}, ErrKo},
+				{[]int{2}, ErrOccupied},
+				{[]int{3, 0, 0, 0}, ErrSuicide},
+				{[]int{4}, ErrInvalidValue},
+			},
+		},
+		{
+			rules: Rules{AllowSuicide: true, Superko: PositionalSuperko},
+			problems: []problem{
+				{[]int{0, 0, 0, 0}, ErrSuperko},
+				{[]int{1, 0}, ErrKo},
+				{[]int{2}, ErrOccupied},
+				// single stone suicide repeats the previous position
+				{[]int{3, 0, 0, 0}, ErrSuperko},
+				{[]int{4}, ErrInvalidValue},
+			},
+		},
+		{
+			rules: Rules{Superko: SituationalSuperko},
+			problems: []problem{
+				{[]int{0, 0, 0, 0}, ErrSuperko},
+				{[]int{1, 0}, ErrKo},
+				{[]int{2}, ErrOccupied},
+				{[]int{3, 0, 0, 0}, ErrSuicide},
+				{[]int{4}, ErrInvalidValue},
+			},
+		},
+	}
+	for i, test := range cases {
+		errs, err := tree.CheckMoves(test.rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(errs) != len(test.problems) {
+			t.Errorf("%d: expected %d problems, got %v", i, len(test.problems), errs)
+			continue
+		}
+		for j, p := range test.problems {
+			if d := cmp.Diff(p.path, errs[j].Path); d != "" {
+				t.Errorf("%d.%d: wrong path (-want +got):\n%s", i, j, d)
+			}
+			if !errors.Is(errs[j], p.err) {
+				t.Errorf("%d.%d: expected %v, got %v", i, j, p.err, errs[j].Err)
+			}
+		}
+	}
+}
+
+func TestCheckMovesPassIsNotSuperko(t *testing.T) {
+	tree := readTree(t, "(;SZ[9];B[ee];W[];B[];W[])")
+	errs, err := tree.CheckMoves(Rules{Superko: PositionalSuperko})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 0 {
+		t.Errorf("unexpected problems %v", errs)
+	}
+}
@@ -0,0 +1,146 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import "errors"
+
+// These values can be returned by a WalkFunc to control the walk.  Walk
+// and WalkBFS never return these values.
+var (
+	// SkipSubtree indicates that the children of the current node
+	// should not be visited.
+	SkipSubtree = errors.New("skip this subtree")
+
+	// SkipAll indicates that the walk should stop.
+	SkipAll = errors.New("skip everything")
+)
+
+// WalkFunc is the type of the function called by Walk and WalkBFS for
+// each node.  The depth of the root node is 0, and moveNumber is
+// computed as described for Cursor.MoveNumber.  The path is only valid
+// during the call; it must be copied if it is retained.
+//
+// If the function returns SkipSubtree, the children of n are not
+// visited.  If it returns SkipAll, the walk stops.  If it returns any
+// other non-nil error, the walk stops and the error is returned.
+type WalkFunc func(n *Tree, depth int, path Path, moveNumber int) error
+
+// Walk visits all nodes of the game tree with root t in depth-first
+// order, i.e. every node is visited before its children, and the
+// variations are visited in order.
+func (t *Tree) Walk(fn WalkFunc) error {
+	type task struct {
+		node       *Tree
+		depth, idx int
+		moveNumber int // move number of the parent
+	}
+
+	var path Path
+	stack := []task{{node: t}}
+	for len(stack) > 0 {
+		tk := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		n := tk.node
+		if tk.depth > 0 {
+			path = append(path[:tk.depth-1], tk.idx)
+		}
+		mn := n.moveNumber(tk.moveNumber)
+		err := fn(n, tk.depth, path, mn)
+		if err == SkipSubtree {
+			continue
+		} else if err == SkipAll {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		for i := len(n.Children) - 1; i >= 0; i-- {
+			stack = append(stack, task{n.Children[i], tk.depth + 1, i, mn})
+		}
+	}
+	return nil
+}
+
+// WalkBFS visits all nodes of the game tree with root t in breadth-first
+// order, i.e. all nodes of depth d are visited before any node of depth
+// d+1.
+func (t *Tree) WalkBFS(fn WalkFunc) error {
+	type task struct {
+		node       *Tree
+		path       Path
+		moveNumber int // move number of the parent
+	}
+
+	queue := []task{{node: t}}
+	for len(queue) > 0 {
+		tk := queue[0]
+		queue[0] = task{}
+		queue = queue[1:]
+
+		n := tk.node
+		mn := n.moveNumber(tk.moveNumber)
+		err := fn(n, len(tk.path), tk.path, mn)
+		if err == SkipSubtree {
+			continue
+		} else if err == SkipAll {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		for i, child := range n.Children {
+			path := make(Path, len(tk.path)+1)
+			copy(path, tk.path)
+			path[len(tk.path)] = i
+			queue = append(queue, task{child, path, mn})
+		}
+	}
+	return nil
+}
+
+// Walk calls Tree.Walk for every game tree in the collection.  Paths are
+// given relative to the root of each game tree.  If fn returns SkipAll,
+// the remaining game trees are not visited.
+func (c Collection) Walk(fn WalkFunc) error {
+	return c.walk(fn, (*Tree).Walk)
+}
+
+// WalkBFS calls Tree.WalkBFS for every game tree in the collection.
+// Paths are given relative to the root of each game tree.  If fn returns
+// SkipAll, the remaining game trees are not visited.
+func (c Collection) WalkBFS(fn WalkFunc) error {
+	return c.walk(fn, (*Tree).WalkBFS)
+}
+
+func (c Collection) walk(fn WalkFunc, walk func(*Tree, WalkFunc) error) error {
+	stopped := false
+	wrapped := func(n *Tree, depth int, path Path, moveNumber int) error {
+		err := fn(n, depth, path, moveNumber)
+		if err == SkipAll {
+			stopped = true
+		}
+		return err
+	}
+	for _, t := range c {
+		err := walk(t, wrapped)
+		if err != nil || stopped {
+			return err
+		}
+	}
+	return nil
+}
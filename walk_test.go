@@ -0,0 +1,152 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const walkTestTree = "(;GM[1];B[aa](;W[bb];B[cc])(;W[dd];B[ee]MN[20];W[ff])(;W[gg]))"
+
+type walkRecord struct {
+	Move       string
+	Depth      int
+	Path       string
+	MoveNumber int
+}
+
+func recordWalk(res *[]walkRecord, skip string) WalkFunc {
+	return func(n *Tree, depth int, path Path, moveNumber int) error {
+		m := moveOf(n)
+		*res = append(*res, walkRecord{m, depth, path.String(), moveNumber})
+		switch {
+		case m == skip && skip != "":
+			return SkipSubtree
+		case m == "Bee" && skip == "stop":
+			return SkipAll
+		}
+		return nil
+	}
+}
+
+func TestWalk(t *testing.T) {
+	tree := readTree(t, walkTestTree)
+
+	var res []walkRecord
+	err := tree.Walk(recordWalk(&res, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []walkRecord{
+		{"", 0, "0", 0},
+		{"Baa", 1, "1", 1},
+		{"Wbb", 2, "2", 2},
+		{"Bcc", 3, "3", 3},
+		{"Wdd", 2, "1.1", 2},
+		{"Bee", 3, "1.1.1", 20},
+		{"Wff", 4, "1.1.2", 21},
+		{"Wgg", 2, "1.2", 2},
+	}
+	if d := cmp.Diff(expected, res); d != "" {
+		t.Errorf("wrong DFS order (-want +got):\n%s", d)
+	}
+
+	res = nil
+	err = tree.WalkBFS(recordWalk(&res, "Wdd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []walkRecord{
+		{"", 0, "0", 0},
+		{"Baa", 1, "1", 1},
+		{"Wbb", 2, "2", 2},
+		{"Wdd", 2, "1.1", 2},
+		{"Wgg", 2, "1.2", 2},
+		{"Bcc", 3, "3", 3},
+	}
+	if d := cmp.Diff(expected, res); d != "" {
+		t.Errorf("wrong BFS order (-want +got):\n%s", d)
+	}
+}
+
+func TestWalkStop(t *testing.T) {
+	c, err := Read(strings.NewReader(walkTestTree + walkTestTree))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res []walkRecord
+	err = c.Walk(recordWalk(&res, "stop"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 6 || res[5].Move != "Bee" {
+		t.Errorf("walk did not stop: %v", res)
+	}
+
+	res = nil
+	err = c.WalkBFS(recordWalk(&res, "Baa"))
+	if err != nil || len(res) != 4 {
+		t.Errorf("wrong BFS walk of collection: %v, %v", res, err)
+	}
+
+	errTest := errors.New("test")
+	count := 0
+	err = c.Walk(func(n *Tree, depth int, path Path, moveNumber int) error {
+		count++
+		return errTest
+	})
+	if err != errTest || count != 1 {
+		t.Errorf("expected test error after one node, got %v after %d", err, count)
+	}
+}
+
+func TestDeepNesting(t *testing.T) {
+	const depth = 100000
+	in := strings.Repeat("(;B[aa]", depth) + strings.Repeat(")", depth)
+	c, err := Read(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := 0
+	err = c.Walk(func(n *Tree, d int, path Path, moveNumber int) error {
+		nodes++
+		return nil
+	})
+	if err != nil || nodes != depth {
+		t.Fatalf("expected %d nodes, got %d (%v)", depth, nodes, err)
+	}
+
+	buf := &bytes.Buffer{}
+	err = c.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c2[0].IsLinear() || len(c2[0].MainVariation()) != depth {
+		t.Error("round trip failed")
+	}
+}
@@ -18,40 +18,105 @@ package sgf
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"sort"
 
 	"golang.org/x/exp/maps"
 )
 
+// Write writes the collection to w, in SGF format.
 func (c Collection) Write(w io.Writer) error {
-	buf := bufio.NewWriter(w)
+	return c.WriteWithOptions(w, nil)
+}
+
+// WriteOptions controls how a collection is written.
+type WriteOptions struct {
+	// Charset, if non-empty, is the character set used for the output.
+	// Property values are converted from UTF-8 to this character set,
+	// and the CA property of each game tree is set to Charset in the
+	// output.  The collection itself is not modified.
+	// See RegisterCharset for the available character sets.
+	Charset string
+}
+
+// WriteWithOptions writes the collection to w, in SGF format.  If opt is
+// nil, default options are used.
+//
+// Property values are written in raw form, see Properties.  If a property
+// identifier or a raw value is not well-formed, a *PropertyError is
+// returned.
+func (c Collection) WriteWithOptions(w io.Writer, opt *WriteOptions) error {
+	var cs Charset
+	if opt != nil && opt.Charset != "" {
+		cs = LookupCharset(opt.Charset)
+		if cs == nil {
+			return fmt.Errorf("unknown character set %q", opt.Charset)
+		}
+	}
 
+	buf := bufio.NewWriter(w)
 	for _, g := range c {
-		g.write(buf)
+		if cs != nil {
+			root := &Tree{
+				Properties: maps.Clone(g.Properties),
+				Children:   g.Children,
+			}
+			root.Properties["CA"] = []string{opt.Charset}
+			g = root
+		}
+		err := g.write(buf, cs)
+		if err != nil {
+			return err
+		}
 	}
 
 	_, _ = buf.WriteRune('\n')
 	return buf.Flush()
 }
 
-func (g *Tree) write(buf *bufio.Writer) {
-	_, _ = buf.WriteRune('(')
-	for {
-		g.Properties.write(buf)
-		if len(g.Children) != 1 {
-			break
+// write writes the game tree to buf.  If cs is non-nil, property values
+// are converted to the character set cs.
+//
+// Variations are handled using an explicit stack instead of recursion, so
+// that deeply nested trees cannot exhaust the call stack.
+func (g *Tree) write(buf *bufio.Writer, cs Charset) error {
+	// A nil entry on the stack stands for the closing bracket of a game
+	// tree.
+	stack := []*Tree{g}
+	first := true
+	for len(stack) > 0 {
+		g := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if g == nil {
+			_, _ = buf.WriteRune(')')
+			continue
+		}
+
+		if !first {
+			_, _ = buf.WriteRune('\n')
+		}
+		first = false
+		_, _ = buf.WriteRune('(')
+		for {
+			err := g.Properties.write(buf, cs)
+			if err != nil {
+				return err
+			}
+			if len(g.Children) != 1 {
+				break
+			}
+			g = g.Children[0]
+		}
+		stack = append(stack, nil)
+		for i := len(g.Children) - 1; i >= 0; i-- {
+			stack = append(stack, g.Children[i])
 		}
-		g = g.Children[0]
-	}
-	for _, c := range g.Children {
-		_, _ = buf.WriteRune('\n')
-		c.write(buf)
 	}
-	_, _ = buf.WriteRune(')')
+	return nil
 }
 
-func (n Properties) write(buf *bufio.Writer) {
+func (n Properties) write(buf *bufio.Writer, cs Charset) error {
 	_, _ = buf.WriteRune(';')
 	keys := maps.Keys(n)
 	sort.Strings(keys)
@@ -59,11 +124,28 @@ func (n Properties) write(buf *bufio.Writer) {
 		if j > 0 {
 			_, _ = buf.WriteRune('\n')
 		}
+		values := n[key]
+		if !isValidIdent(key) {
+			return newPropertyError(key, "", ErrInvalidIdent)
+		} else if len(values) == 0 {
+			return newPropertyError(key, "", ErrValueCount)
+		}
 		_, _ = buf.WriteString(key)
-		for _, value := range n[key] {
+		for _, value := range values {
+			if !isValidRaw(value) {
+				return newPropertyError(key, value, ErrInvalidValue)
+			}
+			if cs != nil {
+				enc, err := cs.Encode(value)
+				if err != nil {
+					return newPropertyError(key, value, err)
+				}
+				value = enc
+			}
 			_, _ = buf.WriteRune('[')
 			_, _ = buf.WriteString(value)
 			_, _ = buf.WriteRune(']')
 		}
 	}
+	return nil
 }
@@ -0,0 +1,71 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import "sync"
+
+// A zobristTable holds the random values used to compute Zobrist hashes
+// of positions on a board of a given size.
+type zobristTable struct {
+	stones []uint64 // two values per point, for black and white stones
+	toPlay [3]uint64
+}
+
+var zobristTables sync.Map // BoardSize -> *zobristTable
+
+// getZobristTable returns the table for boards of size sz.  The values
+// are generated deterministically, so that hashes are reproducible
+// between runs of a program.
+func getZobristTable(sz BoardSize) *zobristTable {
+	if t, ok := zobristTables.Load(sz); ok {
+		return t.(*zobristTable)
+	}
+
+	// splitmix64, seeded with the board size
+	state := uint64(sz.Width)<<32 | uint64(sz.Height)
+	next := func() uint64 {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		return z ^ (z >> 31)
+	}
+
+	t := &zobristTable{
+		stones: make([]uint64, 2*sz.Width*sz.Height),
+	}
+	for i := range t.stones {
+		t.stones[i] = next()
+	}
+	t.toPlay[Black] = next()
+	t.toPlay[White] = next()
+
+	res, _ := zobristTables.LoadOrStore(sz, t)
+	return res.(*zobristTable)
+}
+
+// key returns the hash contribution of a stone of color c at idx.
+func (t *zobristTable) key(idx int, c Color) uint64 {
+	switch c {
+	case Black:
+		return t.stones[2*idx]
+	case White:
+		return t.stones[2*idx+1]
+	default:
+		return 0
+	}
+}
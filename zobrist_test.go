@@ -0,0 +1,82 @@
+// seehuhn.de/go/sgf - read and write Smart Game Format (SGF) files
+// Copyright (C) 2022  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sgf
+
+import "testing"
+
+func TestHashTransposition(t *testing.T) {
+	tree := readTree(t, "(;SZ[9](;B[cc];W[gg];B[cg];W[gc])(;B[cg];W[gc];B[cc];W[gg]))")
+	a, err := tree.PositionAt([]int{0, 0, 0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := tree.PositionAt([]int{1, 0, 0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Hash() != b.Hash() || a.SituationalHash() != b.SituationalHash() {
+		t.Error("equal positions have different hashes")
+	}
+
+	c, err := tree.PositionAt([]int{0, 0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Hash() == a.Hash() {
+		t.Error("different positions have equal hashes")
+	}
+}
+
+func TestHashIncremental(t *testing.T) {
+	b := boardFromDiagram(t, `
+		.XO..
+		XO.O.
+		.XO..
+		.....`)
+	_, err := b.Play(Black, Move{2, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// compare with a board set up directly
+	c := boardFromDiagram(t, `
+		.XO..
+		X.XO.
+		.XO..
+		.....`)
+	if b.Hash() != c.Hash() {
+		t.Error("incremental hash differs from hash of set up position")
+	}
+	if b.Clone().Hash() != b.Hash() {
+		t.Error("clone has different hash")
+	}
+	if NewBoard(b.Size).Hash() != 0 {
+		t.Error("empty board has non-zero hash")
+	}
+}
+
+func TestSituationalHash(t *testing.T) {
+	p := &Position{Board: NewBoard(BoardSize{9, 9}), ToPlay: Black}
+	q := p.Clone()
+	q.ToPlay = White
+	if p.Hash() != q.Hash() {
+		t.Error("positional hash depends on the player to move")
+	}
+	if p.SituationalHash() == q.SituationalHash() {
+		t.Error("situational hash does not depend on the player to move")
+	}
+}